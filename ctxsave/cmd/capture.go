@@ -24,6 +24,9 @@ func init() {
 	captureCmd.AddCommand(captureGitCmd)
 	captureCmd.AddCommand(captureNoteCmd)
 	captureCmd.AddCommand(captureFileCmd)
+	captureCmd.AddCommand(captureAiderCmd)
+	captureCmd.AddCommand(captureCopilotCmd)
+	captureCmd.AddCommand(captureContinueCmd)
 
 	captureGitCmd.Flags().StringVar(&gitSince, "since", "", "git log --since value (e.g. '4h', '1d')")
 	captureGitCmd.Flags().IntVar(&gitCommits, "commits", 10, "max number of commits to capture")
@@ -62,13 +65,69 @@ var captureCursorCmd = &cobra.Command{
 			return err
 		}
 
-		fmt.Printf("Auto-captured %d new transcripts (%d already processed)\n", result.Captured, result.Skipped)
-		for _, e := range result.Errors {
-			fmt.Printf("  warning: %s\n", e)
+		reportAutoCapture(result, "transcripts", "Cursor transcripts")
+		return nil
+	},
+}
+
+var captureCopilotCmd = &cobra.Command{
+	Use:   "copilot [path-to-session-json]",
+	Short: "Import VS Code Copilot Chat sessions (auto-detects if no path given)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		if len(args) == 1 {
+			sess, err := capture.CaptureFromCopilot(st, args[0], project)
+			if err != nil {
+				return err
+			}
+			count, _ := st.CountEntries(sess.ID)
+			fmt.Printf("Captured %d entries from Copilot Chat session → session %s\n", count, sess.ID)
+			return nil
 		}
-		if result.Captured == 0 && result.Skipped == 0 {
-			fmt.Println("No Cursor transcripts found for this project.")
+
+		dir, _ := os.Getwd()
+		result, err := capture.CaptureAllFromCopilot(st, dir, project)
+		if err != nil {
+			return err
 		}
+		reportAutoCapture(result, "sessions", "Copilot Chat sessions")
+		return nil
+	},
+}
+
+var captureContinueCmd = &cobra.Command{
+	Use:   "continue [path-to-session-json]",
+	Short: "Import Continue.dev sessions (auto-detects if no path given)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		if len(args) == 1 {
+			sess, err := capture.CaptureFromContinue(st, args[0], project)
+			if err != nil {
+				return err
+			}
+			count, _ := st.CountEntries(sess.ID)
+			fmt.Printf("Captured %d entries from Continue session → session %s\n", count, sess.ID)
+			return nil
+		}
+
+		dir, _ := os.Getwd()
+		result, err := capture.CaptureAllFromContinue(st, dir, project)
+		if err != nil {
+			return err
+		}
+		reportAutoCapture(result, "sessions", "Continue sessions")
 		return nil
 	},
 }
@@ -95,6 +154,28 @@ var captureGitCmd = &cobra.Command{
 	},
 }
 
+var captureAiderCmd = &cobra.Command{
+	Use:   "aider",
+	Short: "Parse Aider chat history (.aider.chat.history.md) in the project root",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		dir, _ := os.Getwd()
+		sess, err := capture.CaptureFromAider(st, dir, project)
+		if err != nil {
+			return err
+		}
+
+		count, _ := st.CountEntries(sess.ID)
+		fmt.Printf("Captured %d entries from Aider history → session %s\n", count, sess.ID)
+		return nil
+	},
+}
+
 var captureNoteCmd = &cobra.Command{
 	Use:   "note \"your note text\"",
 	Short: "Add a manual context note",
@@ -138,6 +219,16 @@ var captureFileCmd = &cobra.Command{
 	},
 }
 
+func reportAutoCapture(result *capture.AutoCaptureResult, noun, source string) {
+	fmt.Printf("Auto-captured %d new %s (%d already processed)\n", result.Captured, noun, result.Skipped)
+	for _, e := range result.Errors {
+		fmt.Printf("  warning: %s\n", e)
+	}
+	if result.Captured == 0 && result.Skipped == 0 && len(result.Errors) == 0 {
+		fmt.Printf("No %s found for this project.\n", source)
+	}
+}
+
 func openStore() (*store.Store, string, error) {
 	dir, err := os.Getwd()
 	if err != nil {
@@ -153,6 +244,7 @@ func openStore() (*store.Store, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	st.SetBranch(capture.CurrentBranch(dir))
 
 	project := filepath.Base(dir)
 	return st, project, nil
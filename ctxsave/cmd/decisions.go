@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ctxsave/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	decideWhy        string
+	decideStatus     string
+	decideSupersedes int64
+	decideEntry      int64
+	decisionsStatus  string
+)
+
+func init() {
+	rootCmd.AddCommand(decideCmd)
+	rootCmd.AddCommand(decisionsCmd)
+	decisionsCmd.AddCommand(decisionsAcceptCmd)
+
+	decideCmd.Flags().StringVar(&decideWhy, "why", "", "rationale behind the decision")
+	decideCmd.Flags().StringVar(&decideStatus, "status", string(store.DecisionAccepted), "initial status (proposed, accepted)")
+	decideCmd.Flags().Int64Var(&decideSupersedes, "supersedes", 0, "id of an earlier decision this one replaces")
+	decideCmd.Flags().Int64Var(&decideEntry, "entry", 0, "id of the captured entry this decision was made in")
+	decisionsCmd.Flags().StringVar(&decisionsStatus, "status", "", "only list decisions with this status")
+}
+
+var decideCmd = &cobra.Command{
+	Use:   "decide \"decision title\"",
+	Short: "Record a decision with its rationale",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		status := store.DecisionStatus(decideStatus)
+		if status != store.DecisionProposed && status != store.DecisionAccepted {
+			return fmt.Errorf("invalid status %q — use proposed or accepted", decideStatus)
+		}
+
+		if decideEntry > 0 {
+			if _, err := st.GetEntry(decideEntry); err != nil {
+				return fmt.Errorf("entry %d not found", decideEntry)
+			}
+		}
+
+		title := strings.Join(args, " ")
+		d, err := st.AddDecision(title, decideWhy, status, decideSupersedes, decideEntry)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Decision #%d recorded (%s)\n", d.ID, d.Status)
+		if d.Supersedes > 0 {
+			fmt.Printf("Decision #%d marked superseded\n", d.Supersedes)
+		}
+		return nil
+	},
+}
+
+var decisionsCmd = &cobra.Command{
+	Use:   "decisions",
+	Short: "List the decision log",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		decisions, err := st.ListDecisions()
+		if err != nil {
+			return err
+		}
+
+		shown := 0
+		for _, d := range decisions {
+			if decisionsStatus != "" && string(d.Status) != decisionsStatus {
+				continue
+			}
+			if shown == 0 {
+				fmt.Printf("%-5s %-11s %-17s %s\n", "ID", "STATUS", "CREATED", "TITLE")
+				fmt.Println("─────────────────────────────────────────────────────────────────")
+			}
+			shown++
+
+			fmt.Printf("%-5s %-11s %-17s %s\n",
+				fmt.Sprintf("#%d", d.ID),
+				d.Status,
+				d.CreatedAt.Local().Format("2006-01-02 15:04"),
+				d.Title,
+			)
+			if d.Rationale != "" {
+				fmt.Printf("      why: %s\n", d.Rationale)
+			}
+			if d.Supersedes > 0 {
+				fmt.Printf("      supersedes: #%d\n", d.Supersedes)
+			}
+			if d.EntryID > 0 {
+				fmt.Printf("      source entry: %d\n", d.EntryID)
+			}
+		}
+
+		if shown == 0 {
+			fmt.Println("No decisions recorded — run 'ctxsave decide' first")
+		}
+		return nil
+	},
+}
+
+var decisionsAcceptCmd = &cobra.Command{
+	Use:   "accept <decision-id>",
+	Short: "Mark a proposed decision as accepted",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid decision id %q", args[0])
+		}
+		if err := st.SetDecisionStatus(id, store.DecisionAccepted); err != nil {
+			return err
+		}
+
+		fmt.Printf("Decision #%d accepted\n", id)
+		return nil
+	},
+}
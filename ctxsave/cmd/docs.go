@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"ctxsave/internal/capture"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	captureCmd.AddCommand(captureDocsCmd)
+}
+
+var captureDocsCmd = &cobra.Command{
+	Use:   "docs <glob...>",
+	Short: "Capture docs matching the globs, updating each one in place when it changes",
+	Example: `  ctxsave capture docs 'docs/**/*.md' 'adr/*.md'
+  ctxsave capture docs README.md ARCHITECTURE.md`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		dir, _ := os.Getwd()
+		result, err := capture.CaptureDocs(st, dir, project, args)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Docs: %d added, %d updated, %d unchanged", result.Added, result.Updated, result.Unchanged)
+		if result.Removed > 0 {
+			fmt.Printf(", %d removed", result.Removed)
+		}
+		fmt.Println()
+		for _, e := range result.Errors {
+			fmt.Printf("  warning: %s\n", e)
+		}
+		return nil
+	},
+}
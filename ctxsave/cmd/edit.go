@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ctxsave/internal/store"
+	"ctxsave/internal/tui"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+	rootCmd.AddCommand(retypeCmd)
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit <entry-id>",
+	Short: "Edit a captured entry's content in $EDITOR",
+	Long: `Open a captured entry in $VISUAL or $EDITOR and save the edited content.
+The original is kept in the corrections audit trail, and the edit is applied
+again when the same transcript is re-captured.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseEntryID(args[0])
+		if err != nil {
+			return err
+		}
+
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		e, err := st.GetEntry(id)
+		if err != nil {
+			return fmt.Errorf("entry #%d not found", id)
+		}
+
+		f, err := os.CreateTemp("", "ctxsave-entry-*.md")
+		if err != nil {
+			return fmt.Errorf("create temp file: %w", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(e.Content + "\n")
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("write temp file: %w", err)
+		}
+
+		editor := tui.EditorCommand(f.Name())
+		editor.Stdin, editor.Stdout, editor.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := editor.Run(); err != nil {
+			return fmt.Errorf("run editor: %w", err)
+		}
+
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			return fmt.Errorf("read edited entry: %w", err)
+		}
+		content := strings.TrimRight(string(data), "\n")
+		if strings.TrimSpace(content) == "" {
+			return fmt.Errorf("edited content is empty — entry #%d left unchanged", id)
+		}
+		if content == e.Content {
+			fmt.Println("No changes")
+			return nil
+		}
+
+		if err := st.UpdateEntryContent(id, content); err != nil {
+			return err
+		}
+		fmt.Printf("Updated entry #%d\n", id)
+		return nil
+	},
+}
+
+var retypeCmd = &cobra.Command{
+	Use:   "retype <entry-id> <type>",
+	Short: "Reclassify a captured entry, e.g. from conversation to decision",
+	Long: `Change the type of a captured entry. The original type is kept in the
+corrections audit trail, and the change is applied again when the same
+transcript is re-captured.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseEntryID(args[0])
+		if err != nil {
+			return err
+		}
+		entryType, err := parseEntryType(args[1])
+		if err != nil {
+			return err
+		}
+
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		e, err := st.GetEntry(id)
+		if err != nil {
+			return fmt.Errorf("entry #%d not found", id)
+		}
+		if e.Type == entryType {
+			fmt.Printf("Entry #%d is already %s\n", id, entryType)
+			return nil
+		}
+
+		if err := st.SetEntryType(id, entryType); err != nil {
+			return err
+		}
+		fmt.Printf("Entry #%d: %s → %s\n", id, e.Type, entryType)
+		return nil
+	},
+}
+
+func parseEntryType(s string) (store.EntryType, error) {
+	var names []string
+	for _, t := range store.EntryTypes {
+		if string(t) == strings.ToLower(s) {
+			return t, nil
+		}
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("unknown entry type %q — use one of: %s", s, strings.Join(names, ", "))
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"ctxsave/internal/capture"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportMatch []string
+	exportAll   bool
+	exportPick  bool
+)
+
+func init() {
+	captureCmd.AddCommand(captureExportCmd)
+
+	captureExportCmd.Flags().StringSliceVar(&exportMatch, "match", nil, "keywords that, together with the project name, mark a conversation as relevant")
+	captureExportCmd.Flags().BoolVar(&exportAll, "all", false, "import every conversation, not only relevant ones")
+	captureExportCmd.Flags().BoolVar(&exportPick, "pick", false, "choose conversations from an interactive list")
+}
+
+var captureExportCmd = &cobra.Command{
+	Use:   "export <conversations.json|export.zip>",
+	Short: "Import conversations from a ChatGPT or Claude.ai data export",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		convs, err := capture.LoadExport(args[0])
+		if err != nil {
+			return err
+		}
+
+		candidates := convs
+		if !exportAll {
+			dir, _ := os.Getwd()
+			candidates = capture.FilterRelevantConversations(convs, dir, project, exportMatch)
+		}
+
+		if exportPick {
+			list := candidates
+			if len(list) == 0 {
+				list = convs
+			}
+			candidates, err = pickConversations(list)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(candidates) == 0 {
+			fmt.Printf("No relevant conversations among %d in the export — try --match, --pick or --all\n", len(convs))
+			return nil
+		}
+
+		captured, skipped := 0, 0
+		for _, c := range candidates {
+			sess, already, err := capture.CaptureExportConversation(st, c, project)
+			if err != nil {
+				fmt.Printf("  warning: %v\n", err)
+				continue
+			}
+			if already {
+				skipped++
+				continue
+			}
+			captured++
+			count, _ := st.CountEntries(sess.ID)
+			fmt.Printf("  %s → session %s (%d entries)\n", c.Title, sess.ID, count)
+		}
+
+		fmt.Printf("Imported %d conversations (%d already imported)\n", captured, skipped)
+		return nil
+	},
+}
+
+// pickConversations prints a numbered list and reads a selection such as
+// "1,3,5-7" from stdin.
+func pickConversations(convs []capture.ExportConversation) ([]capture.ExportConversation, error) {
+	for i, c := range convs {
+		fmt.Printf("%3d  %-10s %s  %s\n", i+1, c.Source, c.UpdatedAt.Local().Format("2006-01-02"), c.Title)
+	}
+	fmt.Print("Select conversations (e.g. 1,3,5-7): ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("read selection: %w", err)
+	}
+
+	var picked []capture.ExportConversation
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(line, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		lo, hi := field, field
+		if idx := strings.Index(field, "-"); idx > 0 {
+			lo, hi = field[:idx], field[idx+1:]
+		}
+		start, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		end, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || start < 1 || end > len(convs) || start > end {
+			return nil, fmt.Errorf("invalid selection %q", field)
+		}
+		for n := start; n <= end; n++ {
+			if !seen[n] {
+				seen[n] = true
+				picked = append(picked, convs[n-1])
+			}
+		}
+	}
+	return picked, nil
+}
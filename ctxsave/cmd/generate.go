@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"ctxsave/internal/generate"
 
@@ -12,10 +13,19 @@ import (
 )
 
 var (
-	genModel  string
-	genBudget int
-	genCopy   bool
-	genOut    string
+	genModel    string
+	genBudget   int
+	genCopy     bool
+	genOut      string
+	genFormat   string
+	genBranch   string
+	genTag      string
+	genNoLLM    bool
+	genSessions int
+	genSession  []string
+	genFrom     string
+	genTo       string
+	genSources  []string
 )
 
 func init() {
@@ -25,6 +35,15 @@ func init() {
 	generateCmd.Flags().IntVar(&genBudget, "budget", 0, "token budget (0 = auto based on model)")
 	generateCmd.Flags().BoolVar(&genCopy, "copy", false, "copy generated prompt to clipboard")
 	generateCmd.Flags().StringVar(&genOut, "out", "", "write prompt to file")
+	generateCmd.Flags().StringVar(&genFormat, "format", "", "prompt layout: markdown, xml, plain (default: auto based on model)")
+	generateCmd.Flags().StringVar(&genBranch, "branch", "", "only include context captured on this git branch")
+	generateCmd.Flags().StringVar(&genTag, "tag", "", "only include entries and sessions with this tag")
+	generateCmd.Flags().BoolVar(&genNoLLM, "no-llm", false, "use only extractive summaries, even when an LLM summarizer is configured")
+	generateCmd.Flags().IntVar(&genSessions, "sessions", 0, "only include the latest N sessions (0 = all)")
+	generateCmd.Flags().StringSliceVar(&genSession, "session", nil, "only include this session (repeatable)")
+	generateCmd.Flags().StringVar(&genFrom, "from", "", "only include sessions captured since this date, time or window (e.g. 2024-05-01, 3d)")
+	generateCmd.Flags().StringVar(&genTo, "to", "", "only include sessions captured before this date, time or window")
+	generateCmd.Flags().StringSliceVar(&genSources, "source", nil, "only include sessions from these sources (e.g. cursor,git)")
 }
 
 var generateCmd = &cobra.Command{
@@ -37,39 +56,94 @@ var generateCmd = &cobra.Command{
 		}
 		defer st.Close()
 
+		for _, id := range genSession {
+			if _, err := st.GetSession(id); err != nil {
+				return fmt.Errorf("session %q not found", id)
+			}
+		}
+		from, err := parseTimeFlag(genFrom, false)
+		if err != nil {
+			return fmt.Errorf("--from: %w", err)
+		}
+		to, err := parseTimeFlag(genTo, true)
+		if err != nil {
+			return fmt.Errorf("--to: %w", err)
+		}
+
 		gen := generate.NewPromptGenerator(st, project)
 		prompt, err := gen.Generate(generate.GenerateOptions{
-			ModelKey: genModel,
-			Budget:   genBudget,
+			ModelKey:   genModel,
+			Budget:     genBudget,
+			Sessions:   genSessions,
+			SessionIDs: genSession,
+			Sources:    genSources,
+			From:       from,
+			To:         to,
+			Format:     genFormat,
+			Branch:     genBranch,
+			Tag:        genTag,
+			NoLLM:      genNoLLM,
 		})
 		if err != nil {
 			return err
 		}
-
-		if genCopy {
-			if err := clipboard.WriteAll(prompt); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: could not copy to clipboard: %v\n", err)
-			} else {
-				fmt.Println("Prompt copied to clipboard!")
-			}
+		for _, w := range gen.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
 		}
+		return writePrompt(prompt, genCopy, genOut)
+	},
+}
 
-		if genOut != "" {
-			outPath := genOut
-			if !filepath.IsAbs(outPath) {
-				dir, _ := os.Getwd()
-				outPath = filepath.Join(dir, outPath)
-			}
-			if err := os.WriteFile(outPath, []byte(prompt), 0644); err != nil {
-				return fmt.Errorf("write file: %w", err)
-			}
-			fmt.Printf("Prompt written to %s\n", outPath)
+// writePrompt copies the prompt to the clipboard and/or writes it to a
+// file, and prints it when neither is asked for.
+func writePrompt(prompt string, copy bool, out string) error {
+	if copy {
+		if err := clipboard.WriteAll(prompt); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not copy to clipboard: %v\n", err)
+		} else {
+			fmt.Println("Prompt copied to clipboard!")
 		}
+	}
 
-		if !genCopy && genOut == "" {
-			fmt.Println(prompt)
+	if out != "" {
+		outPath := out
+		if !filepath.IsAbs(outPath) {
+			dir, _ := os.Getwd()
+			outPath = filepath.Join(dir, outPath)
 		}
+		if err := os.WriteFile(outPath, []byte(prompt), 0644); err != nil {
+			return fmt.Errorf("write file: %w", err)
+		}
+		fmt.Printf("Prompt written to %s\n", outPath)
+	}
 
-		return nil
-	},
+	if !copy && out == "" {
+		fmt.Println(prompt)
+	}
+	return nil
+}
+
+// parseTimeFlag reads a --from or --to value: a date, a date and time,
+// RFC 3339, or a window back from now such as "3d". A bare date used as
+// the upper bound covers that whole day.
+func parseTimeFlag(s string, upper bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	d, err := parseWindow(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q — use e.g. 2024-05-01, \"2024-05-01 14:00\" or 3d", s)
+	}
+	return time.Now().Add(-d), nil
 }
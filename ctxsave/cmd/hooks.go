@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ctxsave/internal/capture"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+	hooksCmd.AddCommand(hooksRunCmd)
+}
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage git hooks that capture commits, merges and branch switches",
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install post-commit, post-checkout and post-merge hooks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		st.Close()
+
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("locate ctxsave binary: %w", err)
+		}
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+
+		dir, _ := os.Getwd()
+		hooksDir, err := capture.InstallGitHooks(dir, exe)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Installed git hooks in %s\n", hooksDir)
+		fmt.Println("Hooks that were already there still run first. Remove with 'ctxsave hooks uninstall'.")
+		return nil
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove ctxsave's git hooks and restore the ones they wrapped",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := os.Getwd()
+		hooksDir, removed, err := capture.UninstallGitHooks(dir)
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			fmt.Printf("No ctxsave hooks found in %s\n", hooksDir)
+			return nil
+		}
+		fmt.Printf("Removed %d git hooks from %s\n", removed, hooksDir)
+		return nil
+	},
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:    "run <hook> [args...]",
+	Short:  "Capture a git event (called by the installed hooks)",
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		dir, _ := os.Getwd()
+		sess, err := capture.CaptureGitEvent(st, dir, project, args[0], args[1:])
+		if err != nil {
+			return err
+		}
+		if sess != nil {
+			count, _ := st.CountEntries(sess.ID)
+			fmt.Printf("Captured %d entries from %s → session %s\n", count, args[0], sess.ID)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"ctxsave/internal/compress"
+	"ctxsave/internal/generate"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	llmAPI     string
+	llmURL     string
+	llmModel   string
+	llmTimeout time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(llmCmd)
+	llmCmd.AddCommand(llmSetCmd)
+	llmCmd.AddCommand(llmOffCmd)
+
+	llmSetCmd.Flags().StringVar(&llmAPI, "api", compress.APIOllama, "endpoint API: ollama or openai (any OpenAI-compatible server)")
+	llmSetCmd.Flags().StringVar(&llmURL, "url", "", "base URL (default: http://localhost:11434 for ollama, https://api.openai.com/v1 for openai)")
+	llmSetCmd.Flags().StringVar(&llmModel, "model", "", "model name, e.g. llama3.1")
+	llmSetCmd.Flags().DurationVar(&llmTimeout, "timeout", 0, fmt.Sprintf("per-request timeout, e.g. 2m for a slow local model (default %s)", compress.DefaultLLMTimeout))
+	llmSetCmd.MarkFlagRequired("model")
+}
+
+var llmCmd = &cobra.Command{
+	Use:   "llm",
+	Short: "Show the LLM summarizer used for abstractive session summaries",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		cfg, err := generate.LoadLLMConfig(st)
+		if err != nil {
+			return err
+		}
+		if cfg == nil {
+			fmt.Println("No LLM summarizer configured — briefings use extractive summaries.")
+			fmt.Println("Set one with 'ctxsave llm set --model <name>'.")
+			return nil
+		}
+
+		url := cfg.BaseURL
+		if url == "" {
+			url = compress.DefaultBaseURL(cfg.API)
+		}
+		fmt.Printf("API:     %s\n", cfg.API)
+		fmt.Printf("URL:     %s\n", url)
+		fmt.Printf("Model:   %s\n", cfg.Model)
+		if cfg.Timeout > 0 {
+			fmt.Printf("Timeout: %s\n", cfg.Timeout)
+		} else {
+			fmt.Printf("Timeout: %s (default)\n", compress.DefaultLLMTimeout)
+		}
+		if cfg.APIKey != "" {
+			fmt.Println("Key:     set")
+		} else {
+			fmt.Printf("Key:     not set (%s)\n", generate.LLMKeyEnv)
+		}
+		return nil
+	},
+}
+
+var llmSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Summarize sessions with an Ollama or OpenAI-compatible endpoint",
+	Long: `Configure the endpoint 'ctxsave generate' uses for abstractive per-session
+summaries. Summaries are cached, and briefings fall back to extractive
+summaries whenever the endpoint can't be reached. Connecting fails after a
+few seconds, and a request that times out marks the endpoint unavailable
+for the rest of the run. The API key, if one is needed, is read from
+$` + generate.LLMKeyEnv + `.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if llmTimeout < 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+		timeout := ""
+		if llmTimeout > 0 {
+			timeout = llmTimeout.String()
+		}
+		if _, err := compress.NewLLMBackend(compress.LLMConfig{API: llmAPI, BaseURL: llmURL, Model: llmModel}); err != nil {
+			return err
+		}
+
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		for key, value := range map[string]string{
+			generate.LLMAPISetting:     llmAPI,
+			generate.LLMURLSetting:     llmURL,
+			generate.LLMModelSetting:   llmModel,
+			generate.LLMTimeoutSetting: timeout,
+		} {
+			if err := st.SetSetting(key, value); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Sessions will be summarized by %s via %s\n", llmModel, llmAPI)
+		if llmAPI == compress.APIOpenAI && os.Getenv(generate.LLMKeyEnv) == "" && os.Getenv("OPENAI_API_KEY") == "" {
+			fmt.Printf("Set %s if the endpoint needs an API key.\n", generate.LLMKeyEnv)
+		}
+		return nil
+	},
+}
+
+var llmOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Stop using the LLM summarizer",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		if err := st.SetSetting(generate.LLMModelSetting, ""); err != nil {
+			return err
+		}
+		fmt.Println("LLM summarizer turned off — briefings use extractive summaries")
+		return nil
+	},
+}
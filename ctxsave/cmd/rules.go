@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ctxsave/internal/capture"
+	"ctxsave/internal/rules"
+
+	"github.com/spf13/cobra"
+)
+
+var rulesTestAll bool
+
+func init() {
+	rulesTestCmd.Flags().BoolVar(&rulesTestAll, "all", false, "also list messages no rule matched")
+	rulesCmd.AddCommand(rulesTestCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "List the classification rules applied to captured chats",
+	Long: `List the classification rules, read from .ctxsave/rules.yaml, then
+~/.ctxsave/rules.yaml, then the built-in defaults. Rules run in that order.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := os.Getwd()
+		set, err := rules.Load(dir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Loaded from: %s\n\n", strings.Join(set.Sources, ", "))
+		for _, r := range set.Rules {
+			target := ""
+			switch r.Action {
+			case rules.ActionClassify:
+				target = " " + r.Type
+			case rules.ActionTag:
+				target = " " + r.Tag
+			}
+			role := r.Role
+			if role == "" {
+				role = "any"
+			}
+			fmt.Printf("%-20s %-10s %s%s\n", r.Name, role, r.Action, target)
+		}
+		fmt.Printf("\n%d code indicators\n", len(set.CodeIndicators))
+		return nil
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test <transcript>",
+	Short: "Preview how the rules classify a transcript, without capturing it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := os.Getwd()
+		set, err := rules.Load(dir)
+		if err != nil {
+			return err
+		}
+
+		previews, err := capture.PreviewRules(set, args[0])
+		if err != nil {
+			return err
+		}
+
+		var kept, dropped, changed int
+		for i, p := range previews {
+			switch {
+			case p.Drop:
+				dropped++
+			default:
+				kept++
+				if p.Type != p.Original {
+					changed++
+				}
+			}
+			if len(p.Matched) == 0 && !rulesTestAll {
+				continue
+			}
+
+			result := string(p.Type)
+			if p.Drop {
+				result = "dropped"
+			} else if p.Type != p.Original {
+				result = fmt.Sprintf("%s → %s", p.Original, p.Type)
+			}
+			fmt.Printf("%3d %-9s %s\n", i+1, p.Role, result)
+			fmt.Printf("    %s\n", truncateShow(strings.Join(strings.Fields(p.Content), " "), 120))
+			if len(p.Matched) > 0 {
+				fmt.Printf("    rules: %s\n", strings.Join(p.Matched, ", "))
+			}
+			if len(p.Tags) > 0 {
+				fmt.Printf("    tags:  %s\n", strings.Join(p.Tags, ", "))
+			}
+		}
+
+		fmt.Printf("\n%d messages: %d kept (%d reclassified), %d dropped\n", len(previews), kept, changed, dropped)
+		return nil
+	},
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"ctxsave/internal/capture"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run -- <cmd> [args...]",
+	Short: "Run a command and capture its exit code, duration and output tail",
+	Example: `  ctxsave run -- go build ./...
+  ctxsave run -- npm run lint`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		dir, _ := os.Getwd()
+		sess, result, err := capture.RunAndCapture(st, dir, project, args, os.Stdin, os.Stdout, os.Stderr)
+		st.Close()
+		if err != nil {
+			return err
+		}
+
+		status := "ok"
+		if result.Signal != "" {
+			status = result.Signal
+		} else if result.ExitCode != 0 {
+			status = fmt.Sprintf("exit %d", result.ExitCode)
+		}
+		fmt.Fprintf(os.Stderr, "ctxsave: %s (%s, %s) → session %s\n",
+			result.Command, status, result.Duration.Round(time.Millisecond), sess.ID)
+
+		if result.ExitCode != 0 {
+			os.Exit(result.ExitCode)
+		}
+		return nil
+	},
+}
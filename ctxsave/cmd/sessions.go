@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+
+	"ctxsave/internal/similarity"
 
 	"github.com/spf13/cobra"
 )
 
+var showRelated int
+
 func init() {
 	rootCmd.AddCommand(sessionsCmd)
 	rootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().IntVar(&showRelated, "related", 3, "related entries from other sessions to list per entry (0 = none)")
 }
 
 var sessionsCmd = &cobra.Command{
@@ -67,24 +74,80 @@ var showCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		tags, err := st.EntryTags(sess.ID)
+		if err != nil {
+			return err
+		}
+		corrections, err := st.Corrections(sess.ID)
+		if err != nil {
+			return err
+		}
+		var index *similarity.Index
+		inSession := make(map[int64]bool)
+		if showRelated > 0 {
+			if index, err = similarity.Load(st); err != nil {
+				return fmt.Errorf("load similarity index: %w", err)
+			}
+			for _, e := range entries {
+				inSession[e.ID] = true
+			}
+		}
 
 		fmt.Printf("Session: %s\n", sess.ID)
 		fmt.Printf("Created: %s\n", sess.CreatedAt.Local().Format("2006-01-02 15:04:05"))
 		fmt.Printf("Source:  %s\n", sess.Source)
 		fmt.Printf("Label:   %s\n", sess.Label)
+		if sess.Branch != "" {
+			fmt.Printf("Branch:  %s\n", sess.Branch)
+		}
+		if sess.Pinned {
+			fmt.Printf("Pinned:  yes (priority %d)\n", sess.Priority)
+		}
 		fmt.Printf("Entries: %d\n\n", len(entries))
 
 		for _, e := range entries {
-			fmt.Printf("[%s] %s\n", e.Type, truncateShow(e.Content, 200))
+			pin := ""
+			if e.Pinned {
+				pin = "(pinned) "
+			}
+			fmt.Printf("#%d [%s] %s%s\n", e.ID, e.Type, pin, truncateShow(e.Content, 200))
+			if len(tags[e.ID]) > 0 {
+				fmt.Printf("  tags: %s\n", strings.Join(tags[e.ID], ", "))
+			}
+			for _, c := range corrections[e.ID] {
+				if c.Field == "type" {
+					fmt.Printf("  corrected: type %s → %s\n", c.Original, c.Corrected)
+				} else {
+					fmt.Printf("  corrected: content edited (was: %s)\n", truncateShow(c.Original, 80))
+				}
+			}
 			if e.Metadata != "" {
 				fmt.Printf("  meta: %s\n", e.Metadata)
 			}
+			if index != nil {
+				printRelated(index.Related(e.ID, showRelated, 0.25, func(id int64) bool { return inSession[id] }))
+			}
 			fmt.Println()
 		}
 		return nil
 	},
 }
 
+func printRelated(matches []similarity.Match) {
+	if len(matches) == 0 {
+		return
+	}
+	var related []string
+	for _, m := range matches {
+		if m.Duplicate {
+			related = append(related, fmt.Sprintf("#%d (near-duplicate)", m.EntryID))
+		} else {
+			related = append(related, fmt.Sprintf("#%d (%.2f)", m.EntryID, m.Score))
+		}
+	}
+	fmt.Printf("  related: %s\n", strings.Join(related, ", "))
+}
+
 func truncateShow(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctxsave/internal/capture"
+
+	"github.com/spf13/cobra"
+)
+
+const shellLastCaptureSetting = "shell.last_capture"
+
+var (
+	shellSince   string
+	shellName    string
+	shellAllDirs bool
+	shellLimit   int
+)
+
+func init() {
+	captureCmd.AddCommand(captureShellCmd)
+	captureShellCmd.AddCommand(shellInstallHookCmd)
+
+	captureShellCmd.Flags().StringVar(&shellSince, "since", "", "time window, e.g. '4h' or '2d' (default: since the last shell capture, else 8h)")
+	captureShellCmd.Flags().StringVar(&shellName, "shell", "", "bash, zsh, or fish (default: from $SHELL)")
+	captureShellCmd.Flags().BoolVar(&shellAllDirs, "all-dirs", false, "keep commands even when their directory is unknown")
+	captureShellCmd.Flags().IntVar(&shellLimit, "limit", 200, "max commands to keep from histories without timestamps")
+}
+
+var captureShellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Capture shell commands run in this project (bash, zsh, fish)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		now := time.Now()
+		since := now.Add(-8 * time.Hour)
+		if shellSince != "" {
+			d, err := parseWindow(shellSince)
+			if err != nil {
+				return err
+			}
+			since = now.Add(-d)
+		} else if last, found, err := st.GetSetting(shellLastCaptureSetting); err != nil {
+			return err
+		} else if found {
+			if t, err := time.Parse(time.RFC3339, last); err == nil {
+				since = t
+			}
+		}
+
+		dir, _ := os.Getwd()
+		sess, err := capture.CaptureFromShell(st, dir, project, capture.ShellCaptureOptions{
+			Shell:   shellName,
+			Since:   since,
+			AllDirs: shellAllDirs,
+			Limit:   shellLimit,
+		})
+		if err != nil {
+			return err
+		}
+		if err := st.SetSetting(shellLastCaptureSetting, now.UTC().Format(time.RFC3339)); err != nil {
+			return err
+		}
+
+		count, _ := st.CountEntries(sess.ID)
+		fmt.Printf("Captured %d shell commands → session %s\n", count, sess.ID)
+		return nil
+	},
+}
+
+var shellInstallHookCmd = &cobra.Command{
+	Use:   "install-hook [bash|zsh|fish]",
+	Short: "Install a shell hook that records each command's directory and exit code",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := capture.DetectShell()
+		if len(args) == 1 {
+			shell = args[0]
+		}
+
+		hookPath, rcPath, err := capture.InstallShellHook(shell)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Installed %s hook at %s (sourced from %s)\n", shell, hookPath, rcPath)
+		fmt.Println("Open a new shell to start recording commands.")
+		return nil
+	},
+}
+
+// parseWindow accepts Go durations plus a day suffix, e.g. "90m", "4h", "2d".
+func parseWindow(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q — use e.g. 4h or 2d", s)
+	}
+	return d, nil
+}
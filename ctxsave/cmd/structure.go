@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"ctxsave/internal/capture"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	captureCmd.AddCommand(captureStructureCmd)
+}
+
+var captureStructureCmd = &cobra.Command{
+	Use:   "structure",
+	Short: "Snapshot the project's packages, types and signatures (Go AST, ctags for other languages)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		dir, _ := os.Getwd()
+		sess, version, err := capture.CaptureStructure(st, dir, project)
+		if err != nil {
+			return err
+		}
+		if sess == nil {
+			fmt.Printf("Structure unchanged since version %d\n", version)
+			return nil
+		}
+
+		fmt.Printf("Captured structure version %d → session %s\n", version, sess.ID)
+		return nil
+	},
+}
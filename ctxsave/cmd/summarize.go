@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"ctxsave/internal/generate"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sumModel  string
+	sumBudget int
+	sumCopy   bool
+	sumOut    string
+	sumFormat string
+	sumNoLLM  bool
+)
+
+func init() {
+	rootCmd.AddCommand(summarizeCmd)
+
+	summarizeCmd.Flags().StringVar(&sumModel, "model", "sonnet", "target model key (gemini, opus, sonnet, gpt4o)")
+	summarizeCmd.Flags().IntVar(&sumBudget, "budget", 0, "token budget (0 = auto based on model)")
+	summarizeCmd.Flags().BoolVar(&sumCopy, "copy", false, "copy the briefing to clipboard")
+	summarizeCmd.Flags().StringVar(&sumOut, "out", "", "write the briefing to file")
+	summarizeCmd.Flags().StringVar(&sumFormat, "format", "", "prompt layout: markdown, xml, plain (default: auto based on model)")
+	summarizeCmd.Flags().BoolVar(&sumNoLLM, "no-llm", false, "use only extractive summaries, even when an LLM summarizer is configured")
+}
+
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize <session-id>",
+	Short: "Generate a briefing for a single session",
+	Long: `Generate a briefing from one session only. Pinned context, the decision
+log and the project's architecture snapshot are left out, so the briefing
+covers just what happened in that session.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		if _, err := st.GetSession(args[0]); err != nil {
+			return fmt.Errorf("session %q not found", args[0])
+		}
+
+		gen := generate.NewPromptGenerator(st, project)
+		prompt, err := gen.Generate(generate.GenerateOptions{
+			ModelKey:    sumModel,
+			Budget:      sumBudget,
+			SessionIDs:  []string{args[0]},
+			Format:      sumFormat,
+			NoLLM:       sumNoLLM,
+			SessionOnly: true,
+		})
+		if err != nil {
+			return err
+		}
+		for _, w := range gen.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		return writePrompt(prompt, sumCopy, sumOut)
+	},
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ctxsave/internal/generate"
+
+	"github.com/spf13/cobra"
+)
+
+const ruleTargetsSetting = "sync_rules.targets"
+
+var (
+	syncModel   string
+	syncBudget  int
+	syncFormat  string
+	syncTargets []string
+	syncSave    bool
+)
+
+func init() {
+	rootCmd.AddCommand(syncRulesCmd)
+
+	syncRulesCmd.Flags().StringVar(&syncModel, "model", "sonnet", "target model key (gemini, opus, sonnet, gpt4o)")
+	syncRulesCmd.Flags().IntVar(&syncBudget, "budget", 0, "token budget (0 = auto based on model)")
+	syncRulesCmd.Flags().StringVar(&syncFormat, "format", "", "prompt layout: markdown, xml, plain (default: auto based on model)")
+	syncRulesCmd.Flags().StringSliceVar(&syncTargets, "target", nil, "rule files to update: "+strings.Join(generate.RuleTargetKeys(), ", ")+" (default: configured, else based on model)")
+	syncRulesCmd.Flags().BoolVar(&syncSave, "save", false, "remember --target as the default for future runs")
+}
+
+var syncRulesCmd = &cobra.Command{
+	Use:   "sync-rules",
+	Short: "Write the generated briefing into agent rule files (CLAUDE.md, AGENTS.md, ...)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		model, ok := generate.GetModel(syncModel)
+		if !ok {
+			return fmt.Errorf("unknown model %q — run 'ctxsave models' to list", syncModel)
+		}
+
+		targets := syncTargets
+		if len(targets) == 0 {
+			saved, found, err := st.GetSetting(ruleTargetsSetting)
+			if err != nil {
+				return err
+			}
+			if found && saved != "" {
+				targets = strings.Split(saved, ",")
+			} else {
+				targets = []string{generate.DefaultRuleTarget(model)}
+			}
+		}
+
+		var resolved []generate.RuleTarget
+		for _, key := range targets {
+			t, ok := generate.GetRuleTarget(strings.TrimSpace(key))
+			if !ok {
+				return fmt.Errorf("unknown rule target %q — use one of: %s", key, strings.Join(generate.RuleTargetKeys(), ", "))
+			}
+			resolved = append(resolved, t)
+		}
+
+		if syncSave {
+			if len(syncTargets) == 0 {
+				return fmt.Errorf("--save needs at least one --target")
+			}
+			if err := st.SetSetting(ruleTargetsSetting, strings.Join(syncTargets, ",")); err != nil {
+				return err
+			}
+		}
+
+		gen := generate.NewPromptGenerator(st, project)
+		prompt, err := gen.Generate(generate.GenerateOptions{
+			ModelKey: syncModel,
+			Budget:   syncBudget,
+			Format:   syncFormat,
+		})
+		if err != nil {
+			return err
+		}
+		for _, w := range gen.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+
+		dir, _ := os.Getwd()
+		for _, t := range resolved {
+			path, err := generate.WriteRuleFile(dir, t, prompt)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Synced briefing → %s\n", path)
+		}
+		return nil
+	},
+}
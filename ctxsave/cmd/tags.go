@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagSession  string
+	tagRemove   bool
+	pinSession  string
+	pinPriority int
+)
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(tagsCmd)
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+
+	tagCmd.Flags().StringVar(&tagSession, "session", "", "tag this session instead of an entry")
+	tagCmd.Flags().BoolVar(&tagRemove, "remove", false, "remove the tags instead of adding them")
+	pinCmd.Flags().StringVar(&pinSession, "session", "", "pin this session instead of an entry")
+	pinCmd.Flags().IntVar(&pinPriority, "priority", 0, "higher priorities come first in the briefing")
+	unpinCmd.Flags().StringVar(&pinSession, "session", "", "unpin this session instead of an entry")
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag [entry-id] <tag>...",
+	Short: "Tag an entry or session for use with generate --tag",
+	Example: `  ctxsave tag 42 auth security
+  ctxsave tag --session 4121ee5d9dfb4205 auth
+  ctxsave tag 42 security --remove`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		if tagSession != "" {
+			if _, err := st.GetSession(tagSession); err != nil {
+				return fmt.Errorf("session %q not found", tagSession)
+			}
+			if tagRemove {
+				err = st.UntagSession(tagSession, args...)
+			} else {
+				err = st.TagSession(tagSession, args...)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Session %s: %s %s\n", tagSession, tagVerb(tagRemove), strings.Join(args, ", "))
+			return nil
+		}
+
+		if len(args) < 2 {
+			return fmt.Errorf("give an entry id and at least one tag")
+		}
+		id, err := parseEntryID(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := st.GetEntry(id); err != nil {
+			return fmt.Errorf("entry #%d not found", id)
+		}
+		if tagRemove {
+			err = st.UntagEntry(id, args[1:]...)
+		} else {
+			err = st.TagEntry(id, args[1:]...)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Entry #%d: %s %s\n", id, tagVerb(tagRemove), strings.Join(args[1:], ", "))
+		return nil
+	},
+}
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List tags with the number of entries and sessions carrying them",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, _, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		counts, err := st.ListTags()
+		if err != nil {
+			return err
+		}
+		if len(counts) == 0 {
+			fmt.Println("No tags yet — add some with 'ctxsave tag'")
+			return nil
+		}
+
+		fmt.Printf("%-20s %-8s %s\n", "TAG", "ENTRIES", "SESSIONS")
+		fmt.Println("─────────────────────────────────────────")
+		for _, c := range counts {
+			fmt.Printf("%-20s %-8d %d\n", c.Tag, c.Entries, c.Sessions)
+		}
+		return nil
+	},
+}
+
+var pinCmd = &cobra.Command{
+	Use:   "pin [entry-id]",
+	Short: "Pin an entry so it appears verbatim in every briefing",
+	Long: `Pin an entry so it appears verbatim in every briefing, whatever the
+compression level. With --session, pin a whole session instead: its entries
+are kept by every --branch and --tag filter.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPin(args, true)
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin [entry-id]",
+	Short: "Unpin an entry or session",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPin(args, false)
+	},
+}
+
+func setPin(args []string, pinned bool) error {
+	st, _, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	verb := "Pinned"
+	if !pinned {
+		verb = "Unpinned"
+		pinPriority = 0
+	}
+
+	if pinSession != "" {
+		if err := st.SetSessionPin(pinSession, pinned, pinPriority); err != nil {
+			return err
+		}
+		fmt.Printf("%s session %s\n", verb, pinSession)
+		return nil
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("give an entry id, or a session with --session")
+	}
+	id, err := parseEntryID(args[0])
+	if err != nil {
+		return err
+	}
+	if err := st.SetEntryPin(id, pinned, pinPriority); err != nil {
+		return err
+	}
+	fmt.Printf("%s entry #%d\n", verb, id)
+	return nil
+}
+
+func parseEntryID(s string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entry id %q", s)
+	}
+	return id, nil
+}
+
+func tagVerb(remove bool) string {
+	if remove {
+		return "removed"
+	}
+	return "tagged"
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"ctxsave/internal/capture"
+
+	"github.com/spf13/cobra"
+)
+
+var testLabel string
+
+func init() {
+	captureCmd.AddCommand(captureTestCmd)
+
+	captureTestCmd.Flags().StringVar(&testLabel, "label", "", "session label (default: the report format)")
+}
+
+var captureTestCmd = &cobra.Command{
+	Use:   "test [results-file]",
+	Short: "Capture test results from go test -json or JUnit XML (file or stdin)",
+	Example: `  go test -json ./... | ctxsave capture test
+  ctxsave capture test build/test-results/junit.xml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		var data []byte
+		if len(args) == 1 && args[0] != "-" {
+			data, err = os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("read results: %w", err)
+			}
+		} else {
+			if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+				return fmt.Errorf("no input — pipe 'go test -json' output in or pass a results file")
+			}
+			data, err = io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("read stdin: %w", err)
+			}
+		}
+
+		sess, report, err := capture.CaptureTestResults(st, project, data, testLabel)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(report.Summary())
+		fmt.Printf("Captured %d failures → session %s\n", len(report.Failures), sess.ID)
+		return nil
+	},
+}
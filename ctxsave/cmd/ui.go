@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"ctxsave/internal/generate"
+	"ctxsave/internal/tui"
+
+	"github.com/spf13/cobra"
+)
+
+var uiModel string
+
+func init() {
+	uiCmd.Flags().StringVar(&uiModel, "model", "sonnet", "model the preview and token count are generated for (press m to switch)")
+	rootCmd.AddCommand(uiCmd)
+}
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and curate captured context in an interactive terminal UI",
+	Long: `Open a terminal UI with panes for sessions, their entries and a live
+preview of the briefing. Entries can be deleted, pinned, edited in $EDITOR,
+retagged or reclassified, and the preview updates after every change.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, ok := generate.GetModel(uiModel); !ok {
+			return fmt.Errorf("unknown model %q — run 'ctxsave models' to list", uiModel)
+		}
+
+		st, project, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		return tui.Run(st, project, uiModel)
+	},
+}
@@ -0,0 +1,319 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+const (
+	aiderChatHistory  = ".aider.chat.history.md"
+	aiderInputHistory = ".aider.input.history"
+)
+
+// CaptureFromAider parses Aider's chat history in the project root. Both
+// history files are append-only, so each run only parses what was added
+// since the previous capture. The input history is used for user turns
+// when no chat history exists.
+func CaptureFromAider(st *store.Store, projectDir, project string) (*store.Session, error) {
+	chatPath := filepath.Join(projectDir, aiderChatHistory)
+	inputPath := filepath.Join(projectDir, aiderInputHistory)
+
+	chatData, chatSize, err := readAppended(st, chatPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []parsedEntry
+	source, size := "", int64(0)
+
+	if _, statErr := os.Stat(chatPath); statErr == nil {
+		if chatData != nil {
+			entries = parseAiderChat(chatData)
+			source, size = chatPath, chatSize
+		}
+	} else {
+		inputData, inputSize, err := readAppended(st, inputPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, statErr := os.Stat(inputPath); os.IsNotExist(statErr) {
+			return nil, fmt.Errorf("no Aider history found in %s", projectDir)
+		}
+		if inputData != nil {
+			entries = parseAiderInput(inputData)
+			source, size = inputPath, inputSize
+		}
+	}
+
+	if source == "" {
+		return nil, fmt.Errorf("no new Aider history since the last capture")
+	}
+
+	sess, err := st.CreateSession("aider", project, aiderChatHistory)
+	if err != nil {
+		return nil, err
+	}
+	if err := storeParsedEntries(st, sess.ID, entries); err != nil {
+		return nil, err
+	}
+	if err := st.MarkTranscriptProcessed(source, sess.ID, size); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// readAppended returns the bytes added to path since it was last captured,
+// or nil when the file is missing or unchanged. A file that shrank is read
+// from the start again.
+func readAppended(st *store.Store, path string) ([]byte, int64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("read %s: %w", filepath.Base(path), err)
+	}
+
+	offset, err := st.ProcessedTranscriptSize(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	size := int64(len(data))
+	if offset > size {
+		offset = 0
+	}
+	if offset == size {
+		return nil, size, nil
+	}
+	return data[offset:], size, nil
+}
+
+// parseAiderChat turns .aider.chat.history.md into entries. "####" lines are
+// user turns, "> " lines are aider's own tool output, and everything else is
+// the assistant's reply, which may carry SEARCH/REPLACE edit blocks.
+func parseAiderChat(data []byte) []parsedEntry {
+	var entries []parsedEntry
+
+	var user, assistant, output []string
+	runCmd := ""
+
+	flushUser := func() {
+		text := strings.TrimSpace(strings.Join(user, "\n"))
+		user = nil
+		if text == "" {
+			return
+		}
+		if cmd, ok := aiderRunCommand(text); ok {
+			runCmd = cmd
+			return
+		}
+		if strings.HasPrefix(text, "/") {
+			return
+		}
+		if q := extractUserQuery(text); q != "" {
+			entries = append(entries, parsedEntry{
+				Type:    store.EntryConversation,
+				Content: truncate(q, 2000),
+				Meta:    `{"role":"user"}`,
+				Role:    "user",
+			})
+		}
+	}
+
+	flushAssistant := func() {
+		text := strings.Join(assistant, "\n")
+		assistant = nil
+		prose, edits := splitSearchReplace(text)
+		prose = cleanContent(prose)
+		if prose != "" {
+			entries = append(entries, parsedEntry{
+				Type:    store.EntryConversation,
+				Content: truncate(prose, 3000),
+				Meta:    `{"role":"assistant"}`,
+				Role:    "assistant",
+			})
+		}
+		for _, path := range edits {
+			meta, _ := json.Marshal(map[string]string{"source": "aider", "path": path})
+			entries = append(entries, parsedEntry{
+				Type:    store.EntryCodeChange,
+				Content: fmt.Sprintf("Edited %s", path),
+				Meta:    string(meta),
+			})
+		}
+	}
+
+	flushOutput := func() {
+		text := strings.TrimSpace(strings.Join(output, "\n"))
+		output = nil
+		cmd := runCmd
+		runCmd = ""
+		if cmd == "" || text == "" {
+			return
+		}
+		if summary := aiderErrorLine(text); summary != "" {
+			meta, _ := json.Marshal(map[string]string{"source": "aider_run", "command": cmd})
+			entries = append(entries, parsedEntry{
+				Type:    store.EntryError,
+				Content: truncate(fmt.Sprintf("%s (from `%s`)\n%s", summary, cmd, text), 500),
+				Meta:    string(meta),
+				Role:    "tool",
+			})
+		}
+	}
+
+	flushAll := func() {
+		flushUser()
+		flushAssistant()
+		flushOutput()
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# aider chat started at"):
+			flushAll()
+			runCmd = ""
+
+		case strings.HasPrefix(line, "####"):
+			if len(assistant) > 0 || len(output) > 0 {
+				flushAssistant()
+				flushOutput()
+			}
+			user = append(user, strings.TrimSpace(strings.TrimPrefix(line, "####")))
+
+		case line == ">" || strings.HasPrefix(line, "> "):
+			flushUser()
+			if len(assistant) > 0 {
+				flushAssistant()
+			}
+			text := strings.TrimPrefix(strings.TrimPrefix(line, ">"), " ")
+			if !strings.HasPrefix(text, "Add the output to the chat?") {
+				output = append(output, text)
+			}
+
+		default:
+			if len(user) > 0 {
+				flushUser()
+			}
+			if len(output) > 0 && strings.TrimSpace(line) != "" {
+				flushOutput()
+			}
+			if len(output) == 0 {
+				assistant = append(assistant, line)
+			}
+		}
+	}
+	flushAll()
+
+	return entries
+}
+
+// parseAiderInput reads .aider.input.history, where each prompt follows a
+// "# <timestamp>" header and every line of it is prefixed with "+".
+func parseAiderInput(data []byte) []parsedEntry {
+	var entries []parsedEntry
+	var current []string
+
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(current, "\n"))
+		current = nil
+		if text == "" || strings.HasPrefix(text, "/") {
+			return
+		}
+		if q := extractUserQuery(text); q != "" {
+			entries = append(entries, parsedEntry{
+				Type:    store.EntryConversation,
+				Content: truncate(q, 2000),
+				Meta:    `{"role":"user"}`,
+				Role:    "user",
+			})
+		}
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# "):
+			flush()
+		case strings.HasPrefix(line, "+"):
+			current = append(current, strings.TrimPrefix(line, "+"))
+		}
+	}
+	flush()
+
+	return entries
+}
+
+// aiderRunCommand recognizes the chat commands that run a shell command and
+// feed its output back into the chat.
+func aiderRunCommand(text string) (string, bool) {
+	switch {
+	case strings.HasPrefix(text, "/run "):
+		return strings.TrimSpace(strings.TrimPrefix(text, "/run ")), true
+	case strings.HasPrefix(text, "/test "):
+		return strings.TrimSpace(strings.TrimPrefix(text, "/test ")), true
+	case strings.HasPrefix(text, "!"):
+		return strings.TrimSpace(strings.TrimPrefix(text, "!")), true
+	case text == "/lint" || strings.HasPrefix(text, "/lint "):
+		return text, true
+	}
+	return "", false
+}
+
+// aiderErrorLine returns the first output line that reports a failure.
+func aiderErrorLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "error") || strings.Contains(lower, "fail") || strings.Contains(lower, "panic") {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
+
+// splitSearchReplace removes SEARCH/REPLACE blocks (and their code fences)
+// from an assistant reply and returns the remaining prose along with the
+// file path each block edits. Aider puts the path on the line before the
+// opening fence.
+func splitSearchReplace(text string) (string, []string) {
+	lines := strings.Split(text, "\n")
+	var prose []string
+	var paths []string
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "<<<<<<< SEARCH" {
+			prose = append(prose, lines[i])
+			continue
+		}
+
+		// Drop the fence and the filename line that precede the block.
+		path := ""
+		for len(prose) > 0 {
+			last := strings.TrimSpace(prose[len(prose)-1])
+			prose = prose[:len(prose)-1]
+			if strings.HasPrefix(last, "```") {
+				continue
+			}
+			path = last
+			break
+		}
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+
+		for i < len(lines) && strings.TrimSpace(lines[i]) != ">>>>>>> REPLACE" {
+			i++
+		}
+		if i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "```") {
+			i++
+		}
+	}
+
+	return strings.Join(prose, "\n"), paths
+}
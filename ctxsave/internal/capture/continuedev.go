@@ -0,0 +1,141 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+type continueSession struct {
+	Title              string `json:"title"`
+	WorkspaceDirectory string `json:"workspaceDirectory"`
+	History            []struct {
+		Message struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"message"`
+	} `json:"history"`
+}
+
+func FindContinueSessionsDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+
+	dir := filepath.Join(homeDir, ".continue", "sessions")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return "", fmt.Errorf("Continue sessions directory not found at %s", dir)
+	}
+	return dir, nil
+}
+
+// CaptureAllFromContinue captures the Continue.dev sessions whose workspace
+// directory is projectDir. Sessions from other workspaces are left alone and
+// not marked as processed.
+func CaptureAllFromContinue(st *store.Store, projectDir, project string) (*AutoCaptureResult, error) {
+	dir, err := FindContinueSessionsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("scan sessions dir: %w", err)
+	}
+
+	want := filepath.Clean(projectDir)
+	var files []string
+	for _, file := range matches {
+		if filepath.Base(file) == "sessions.json" {
+			continue // index of all sessions, not a session
+		}
+		cs, err := readContinueSession(file)
+		if err != nil || cs.WorkspaceDirectory == "" {
+			continue
+		}
+		if filepath.Clean(cs.WorkspaceDirectory) == want {
+			files = append(files, file)
+		}
+	}
+
+	return captureFiles(st, files, func(file string) (*store.Session, error) {
+		return CaptureFromContinue(st, file, project)
+	}), nil
+}
+
+func CaptureFromContinue(st *store.Store, sessionPath, project string) (*store.Session, error) {
+	cs, err := readContinueSession(sessionPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []parsedEntry
+	for _, item := range cs.History {
+		text := continueContent(item.Message.Content)
+		if text == "" {
+			continue
+		}
+		entries = append(entries, messageEntries(item.Message.Role, text)...)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no messages found in Continue session")
+	}
+
+	label := cs.Title
+	if label == "" {
+		label = filepath.Base(sessionPath)
+	}
+	sess, err := st.CreateSession("continue", project, label)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.SetSessionOrigin(sess.ID, absPath(sessionPath)); err != nil {
+		return nil, err
+	}
+	if err := storeParsedEntries(st, sess.ID, entries); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func readContinueSession(path string) (*continueSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open session: %w", err)
+	}
+	var cs continueSession
+	if err := json.Unmarshal(data, &cs); err != nil {
+		return nil, fmt.Errorf("parse Continue session: %w", err)
+	}
+	return &cs, nil
+}
+
+// continueContent flattens message content, which is either a string or a
+// list of typed parts.
+func continueContent(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
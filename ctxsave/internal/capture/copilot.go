@@ -0,0 +1,199 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+type copilotSession struct {
+	Requests []struct {
+		Message struct {
+			Text string `json:"text"`
+		} `json:"message"`
+		Response []json.RawMessage `json:"response"`
+	} `json:"requests"`
+}
+
+type copilotResponsePart struct {
+	Kind  string          `json:"kind"`
+	Value json.RawMessage `json:"value"`
+	URI   struct {
+		Path string `json:"path"`
+	} `json:"uri"`
+}
+
+// vscodeWorkspaceStorageDirs lists the workspaceStorage roots of the VS Code
+// builds installed for this user.
+func vscodeWorkspaceStorageDirs() []string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var base string
+	switch runtime.GOOS {
+	case "darwin":
+		base = filepath.Join(homeDir, "Library", "Application Support")
+	case "windows":
+		base = os.Getenv("APPDATA")
+	default:
+		base = filepath.Join(homeDir, ".config")
+	}
+
+	var dirs []string
+	for _, app := range []string{"Code", "Code - Insiders", "VSCodium"} {
+		dir := filepath.Join(base, app, "User", "workspaceStorage")
+		if _, err := os.Stat(dir); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// FindCopilotChatDirs returns the chatSessions directories of every VS Code
+// workspace whose folder is projectDir. VS Code names workspace storage by
+// hash, so the folder is matched through each workspace.json.
+func FindCopilotChatDirs(projectDir string) ([]string, error) {
+	roots := vscodeWorkspaceStorageDirs()
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("VS Code workspaceStorage directory not found")
+	}
+
+	want := filepath.Clean(projectDir)
+	var dirs []string
+	for _, root := range roots {
+		workspaces, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, ws := range workspaces {
+			data, err := os.ReadFile(filepath.Join(root, ws.Name(), "workspace.json"))
+			if err != nil {
+				continue
+			}
+			var meta struct {
+				Folder string `json:"folder"`
+			}
+			if json.Unmarshal(data, &meta) != nil || meta.Folder == "" {
+				continue
+			}
+			u, err := url.Parse(meta.Folder)
+			if err != nil || u.Scheme != "file" || filepath.Clean(u.Path) != want {
+				continue
+			}
+			chatDir := filepath.Join(root, ws.Name(), "chatSessions")
+			if _, err := os.Stat(chatDir); err == nil {
+				dirs = append(dirs, chatDir)
+			}
+		}
+	}
+
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no Copilot Chat sessions found for %s", projectDir)
+	}
+	return dirs, nil
+}
+
+func CaptureAllFromCopilot(st *store.Store, projectDir, project string) (*AutoCaptureResult, error) {
+	dirs, err := FindCopilotChatDirs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		files = append(files, matches...)
+	}
+
+	return captureFiles(st, files, func(file string) (*store.Session, error) {
+		return CaptureFromCopilot(st, file, project)
+	}), nil
+}
+
+func CaptureFromCopilot(st *store.Store, sessionPath, project string) (*store.Session, error) {
+	data, err := os.ReadFile(sessionPath)
+	if err != nil {
+		return nil, fmt.Errorf("open session: %w", err)
+	}
+
+	var cs copilotSession
+	if err := json.Unmarshal(data, &cs); err != nil {
+		return nil, fmt.Errorf("parse Copilot session: %w", err)
+	}
+
+	var entries []parsedEntry
+	for _, req := range cs.Requests {
+		entries = append(entries, messageEntries("user", req.Message.Text)...)
+
+		var reply []string
+		var edited []string
+		for _, raw := range req.Response {
+			var part copilotResponsePart
+			if json.Unmarshal(raw, &part) != nil {
+				continue
+			}
+			switch part.Kind {
+			case "textEditGroup":
+				if part.URI.Path != "" {
+					edited = append(edited, part.URI.Path)
+				}
+			case "", "markdownContent":
+				if text := rawText(part.Value); text != "" {
+					reply = append(reply, text)
+				}
+			}
+		}
+
+		entries = append(entries, messageEntries("assistant", strings.Join(reply, ""))...)
+		for _, path := range edited {
+			entries = append(entries, parsedEntry{
+				Type:    store.EntryCodeChange,
+				Content: fmt.Sprintf("Edited %s", shortenPath(path)),
+				Meta:    `{"source":"copilot"}`,
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no messages found in Copilot session")
+	}
+
+	sess, err := st.CreateSession("copilot", project, filepath.Base(sessionPath))
+	if err != nil {
+		return nil, err
+	}
+	if err := st.SetSessionOrigin(sess.ID, absPath(sessionPath)); err != nil {
+		return nil, err
+	}
+	if err := storeParsedEntries(st, sess.ID, entries); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// rawText decodes a value that is either a plain string or an object with a
+// "value" string, as VS Code uses for markdown content.
+func rawText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var md struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &md) == nil {
+		return md.Value
+	}
+	return ""
+}
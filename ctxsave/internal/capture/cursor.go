@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 
+	"ctxsave/internal/rules"
 	"ctxsave/internal/store"
 )
 
@@ -26,12 +27,8 @@ type parsedEntry struct {
 	Type    store.EntryType
 	Content string
 	Meta    string
-}
-
-var decisionKeywords = []string{
-	"decided", "decision", "chose", "going with", "opted for",
-	"design choice", "the fix is", "the solution is", "root cause",
-	"the problem is", "the issue is", "what needs to change",
+	Role    string // user, assistant or tool; what the classification rules match on
+	Tags    []string
 }
 
 var xmlTagsRe = regexp.MustCompile(`</?(?:attached_files|code_selection|user_query|terminal_selection|system_reminder|open_and_recently_viewed_files)[^>]*>`)
@@ -97,6 +94,14 @@ func CaptureAllFromCursor(st *store.Store, projectDir, project string) (*AutoCap
 		return nil, fmt.Errorf("scan transcripts dir: %w", err)
 	}
 
+	return captureFiles(st, files, func(file string) (*store.Session, error) {
+		return CaptureFromCursor(st, file, project)
+	}), nil
+}
+
+// captureFiles runs captureFn on every file not captured before and records
+// each one as processed.
+func captureFiles(st *store.Store, files []string, captureFn func(string) (*store.Session, error)) *AutoCaptureResult {
 	result := &AutoCaptureResult{}
 
 	for _, file := range files {
@@ -110,7 +115,7 @@ func CaptureAllFromCursor(st *store.Store, projectDir, project string) (*AutoCap
 			continue
 		}
 
-		sess, err := CaptureFromCursor(st, file, project)
+		sess, err := captureFn(file)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", filepath.Base(file), err))
 			continue
@@ -128,7 +133,7 @@ func CaptureAllFromCursor(st *store.Store, projectDir, project string) (*AutoCap
 		result.Captured++
 	}
 
-	return result, nil
+	return result
 }
 
 func CaptureFromCursor(st *store.Store, transcriptPath, project string) (*store.Session, error) {
@@ -142,6 +147,9 @@ func CaptureFromCursor(st *store.Store, transcriptPath, project string) (*store.
 	if err != nil {
 		return nil, err
 	}
+	if err := st.SetSessionOrigin(sess.ID, absPath(transcriptPath)); err != nil {
+		return nil, err
+	}
 
 	ext := filepath.Ext(transcriptPath)
 	var parseErr error
@@ -166,10 +174,18 @@ func CaptureFromCursor(st *store.Store, transcriptPath, project string) (*store.
 }
 
 func parseJSONL(st *store.Store, sessionID string, data []byte) error {
+	entries, err := parseJSONLEntries(data)
+	if err != nil {
+		return err
+	}
+	return storeParsedEntries(st, sessionID, entries)
+}
+
+func parseJSONLEntries(data []byte) ([]parsedEntry, error) {
 	scanner := bufio.NewScanner(strings.NewReader(string(data)))
 	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
 
-	orderIdx := 0
+	var entries []parsedEntry
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		if len(line) == 0 {
@@ -181,18 +197,54 @@ func parseJSONL(st *store.Store, sessionID string, data []byte) error {
 			continue
 		}
 
-		entries := extractEntries(tl)
-		for _, pe := range entries {
-			if _, err := st.AddEntry(sessionID, pe.Type, pe.Content, pe.Meta, orderIdx); err != nil {
+		entries = append(entries, extractEntries(tl)...)
+	}
+	return entries, scanner.Err()
+}
+
+// storeParsedEntries runs the classification rules over the entries, pairs
+// errors with their resolutions and writes the entries in order.
+func storeParsedEntries(st *store.Store, sessionID string, entries []parsedEntry) error {
+	set, err := rules.Load(st.Dir())
+	if err != nil {
+		return err
+	}
+	entries = applyRules(set, entries)
+	linkErrorResolutions(entries)
+	for i, pe := range entries {
+		e, err := st.AddEntry(sessionID, pe.Type, pe.Content, pe.Meta, i)
+		if err != nil {
+			return err
+		}
+		if len(pe.Tags) > 0 {
+			if err := st.TagEntry(e.ID, pe.Tags...); err != nil {
 				return err
 			}
-			orderIdx++
 		}
 	}
-	return scanner.Err()
+	return nil
+}
+
+// applyRules reclassifies, redacts and tags the entries, leaving out the
+// ones a rule drops.
+func applyRules(set *rules.Set, entries []parsedEntry) []parsedEntry {
+	var kept []parsedEntry
+	for _, pe := range entries {
+		res := set.Apply(pe.Role, pe.Type, pe.Content)
+		if res.Drop {
+			continue
+		}
+		pe.Type, pe.Content, pe.Tags = res.Type, res.Content, res.Tags
+		kept = append(kept, pe)
+	}
+	return kept
 }
 
 func parseTextTranscript(st *store.Store, sessionID string, data []byte) error {
+	return storeParsedEntries(st, sessionID, parseTextEntries(data))
+}
+
+func parseTextEntries(data []byte) []parsedEntry {
 	content := string(data)
 	lines := strings.Split(content, "\n")
 
@@ -244,7 +296,7 @@ func parseTextTranscript(st *store.Store, sessionID string, data []byte) error {
 		}
 	}
 
-	orderIdx := 0
+	var entries []parsedEntry
 	for _, sec := range sections {
 		text := strings.TrimSpace(sec.content.String())
 		if text == "" {
@@ -253,6 +305,10 @@ func parseTextTranscript(st *store.Store, sessionID string, data []byte) error {
 
 		var entryType store.EntryType
 		var meta string
+		role := sec.role
+		if role == "tool_call" || role == "tool_result" {
+			role = "tool"
+		}
 
 		switch sec.role {
 		case "user":
@@ -267,10 +323,10 @@ func parseTextTranscript(st *store.Store, sessionID string, data []byte) error {
 
 		case "assistant":
 			text = cleanContent(text)
-			if text == "" || isMetaNoise(text) {
+			if text == "" {
 				continue
 			}
-			entryType = classifyAssistantText(text)
+			entryType = store.EntryConversation
 			meta = `{"role":"assistant"}`
 			text = truncate(text, 3000)
 
@@ -297,24 +353,26 @@ func parseTextTranscript(st *store.Store, sessionID string, data []byte) error {
 			continue
 		}
 
-		if _, err := st.AddEntry(sessionID, entryType, text, meta, orderIdx); err != nil {
-			return err
-		}
-		orderIdx++
+		entries = append(entries, parsedEntry{Type: entryType, Content: text, Meta: meta, Role: role})
 	}
 
-	return nil
+	return entries
 }
 
 func extractEntries(tl transcriptLine) []parsedEntry {
-	var entries []parsedEntry
-
 	text := extractText(tl)
 	if text == "" {
-		return entries
+		return nil
 	}
+	return messageEntries(tl.Role, text)
+}
 
-	switch tl.Role {
+// messageEntries classifies one chat message by role. It is shared by every
+// chat-based capture source so they all filter and classify the same way.
+func messageEntries(role, text string) []parsedEntry {
+	var entries []parsedEntry
+
+	switch role {
 	case "user":
 		cleaned := cleanContent(text)
 		query := extractUserQuery(cleaned)
@@ -325,18 +383,19 @@ func extractEntries(tl transcriptLine) []parsedEntry {
 			Type:    store.EntryConversation,
 			Content: truncate(query, 2000),
 			Meta:    `{"role":"user"}`,
+			Role:    role,
 		})
 
 	case "assistant":
 		cleaned := cleanContent(text)
-		if cleaned == "" || isMetaNoise(cleaned) {
+		if cleaned == "" {
 			return entries
 		}
-		entryType := classifyAssistantText(cleaned)
 		entries = append(entries, parsedEntry{
-			Type:    entryType,
+			Type:    store.EntryConversation,
 			Content: truncate(cleaned, 3000),
 			Meta:    `{"role":"assistant"}`,
+			Role:    role,
 		})
 
 	case "tool":
@@ -346,6 +405,7 @@ func extractEntries(tl transcriptLine) []parsedEntry {
 				Type:    store.EntryError,
 				Content: truncate(cleanContent(text), 500),
 				Meta:    `{"source":"tool_result"}`,
+				Role:    role,
 			})
 		}
 	}
@@ -382,91 +442,6 @@ func extractUserQuery(s string) string {
 	return s
 }
 
-// isMetaNoise returns true for content that's about the tooling itself, not the project.
-func isMetaNoise(s string) bool {
-	lower := strings.ToLower(s)
-	noisePatterns := []string{
-		"let me help you find your past chats",
-		"let me look at the content of these chats",
-		"let me also check if you have other cursor",
-		"let me look for chats stored globally",
-		"let me read the full transcript to extract",
-		"let me explore your workspace",
-		"dependencies installed",
-		"all source files written",
-		"build succeeded",
-		"let me tidy deps",
-		"let me clean up the test",
-		"the commands seem to be running but not producing",
-		"the issue is the `cd` in my build command",
-		"let me separate the steps",
-		"let me rebuild",
-		"here's a summary of all your past cursor chats",
-		"i found it. here's what happened with your",
-		"all done. here's what was rebuilt",
-		"your lost project",
-		"ctxsave",
-		".ctxsave/",
-		"ctxsave init",
-		"ctxsave capture",
-		"ctxsave generate",
-		"auto-captured",
-		"second run correctly skips",
-		"let me verify the binary",
-		"fresh init and",
-		"now let me test",
-		"it automatically found and captured",
-		"everything works end-to-end",
-		"i have the full plan and architecture",
-		"let me also install the binary",
-		"now rebuild and test",
-		"now let me do a full end-to-end test",
-		"scaffold go project",
-		"now the capture packages",
-		"now the compress and generate",
-		"now the cli commands",
-		"good, directory structure created",
-		"the output is still too noisy",
-		"much better! now it's selecting",
-		"getting much better",
-		"the structure is clean now",
-		"now it's selecting",
-		"entry count dropped",
-		"noise filtered",
-		"token est",
-		"ctxsave-related",
-		"meta-conversation about",
-		"raw level was selected",
-		"compression level",
-		"token budget",
-	}
-	for _, p := range noisePatterns {
-		if strings.Contains(lower, p) {
-			return true
-		}
-	}
-	return false
-}
-
-func classifyAssistantText(text string) store.EntryType {
-	lower := strings.ToLower(text)
-
-	// Skip thinking-only blocks that just narrate what the AI is about to do
-	if strings.HasPrefix(lower, "now ") && len(text) < 200 {
-		return store.EntryConversation
-	}
-
-	for _, kw := range decisionKeywords {
-		if strings.Contains(lower, kw) {
-			// Only count as decision if there's enough substance
-			if len(text) > 100 {
-				return store.EntryDecision
-			}
-		}
-	}
-	return store.EntryConversation
-}
-
 // summarizeToolCall extracts a meaningful one-line summary from a tool call section.
 func summarizeToolCall(text string) string {
 	lines := strings.Split(strings.TrimSpace(text), "\n")
@@ -538,3 +513,12 @@ func truncate(s string, maxLen int) string {
 	}
 	return s[:maxLen] + "..."
 }
+
+// absPath makes a transcript path independent of the directory a capture
+// was run from, falling back to the path as given.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,330 @@
+package capture
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"ctxsave/internal/store"
+)
+
+// docChunkSize is the most a single doc entry holds; longer docs are split
+// at headings rather than truncated.
+const docChunkSize = 10000
+
+type DocsCaptureResult struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Removed   int
+	Errors    []string
+}
+
+type docChunk struct {
+	Heading string
+	Text    string
+}
+
+// CaptureDocs stores every file under projectDir matching one of the globs.
+// Docs are keyed by their path relative to the project: a doc is only
+// rewritten when its content hash changes, and then in place, so the store
+// holds exactly one current copy of each. Captured docs that match the
+// globs but are gone from disk are removed.
+func CaptureDocs(st *store.Store, projectDir, project string, patterns []string) (*DocsCaptureResult, error) {
+	res, err := docGlobRegexps(projectDir, patterns)
+	if err != nil {
+		return nil, err
+	}
+	files, err := matchDocGlobs(projectDir, res)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DocsCaptureResult{}
+	if result.Removed, err = pruneDocs(st, res, files); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && result.Removed == 0 {
+		return nil, fmt.Errorf("no files match %s", strings.Join(patterns, " "))
+	}
+
+	for _, rel := range files {
+		status, err := captureDoc(st, projectDir, project, rel)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", rel, err))
+			continue
+		}
+		switch status {
+		case "added":
+			result.Added++
+		case "updated":
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+	return result, nil
+}
+
+func captureDoc(st *store.Store, projectDir, project, rel string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	existing, err := st.GetDocument(rel)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.Hash == hash {
+		return "unchanged", nil
+	}
+
+	sessionID := ""
+	status := "added"
+	if existing != nil {
+		sessionID = existing.SessionID
+		status = "updated"
+	} else {
+		sess, err := st.CreateSession("docs", project, fmt.Sprintf("doc: %s", rel))
+		if err != nil {
+			return "", err
+		}
+		sessionID = sess.ID
+	}
+
+	chunks := chunkByHeading(string(data), docChunkSize)
+	var entries []store.Entry
+	for i, c := range chunks {
+		meta := map[string]any{"source": "docs", "path": rel, "hash": hash}
+		if len(chunks) > 1 {
+			meta["chunk"] = i + 1
+			meta["chunks"] = len(chunks)
+		}
+		if c.Heading != "" {
+			meta["heading"] = c.Heading
+		}
+		m, _ := json.Marshal(meta)
+		entries = append(entries, store.Entry{Type: store.EntryFile, Content: c.Text, Metadata: string(m), OrderIdx: i})
+	}
+
+	if err := st.ReplaceDocument(rel, hash, sessionID, entries); err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// pruneDocs removes the captured docs that match one of the globs but are
+// no longer among the files on disk, and returns how many it removed.
+func pruneDocs(st *store.Store, res []*regexp.Regexp, files []string) (int, error) {
+	present := make(map[string]bool)
+	for _, f := range files {
+		present[f] = true
+	}
+	docs, err := st.ListDocuments()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, d := range docs {
+		if present[d.Path] || !matchesAnyGlob(d.Path, res) {
+			continue
+		}
+		if err := st.DeleteSession(d.SessionID); err != nil {
+			return removed, fmt.Errorf("remove %s: %w", d.Path, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func matchesAnyGlob(path string, res []*regexp.Regexp) bool {
+	for _, re := range res {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// docGlobRegexps compiles the patterns against slash-separated project
+// paths. "**" matches across directories; patterns the shell already
+// expanded arrive as plain paths and match themselves.
+func docGlobRegexps(projectDir string, patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		p = filepath.ToSlash(p)
+		if filepath.IsAbs(p) {
+			rel, err := filepath.Rel(projectDir, filepath.FromSlash(p))
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return nil, fmt.Errorf("%s is outside the project", p)
+			}
+			p = filepath.ToSlash(rel)
+		}
+		res = append(res, globRegexp(strings.TrimPrefix(p, "./")))
+	}
+	return res, nil
+}
+
+// matchDocGlobs returns the slash-separated project paths matching any of
+// the patterns, sorted.
+func matchDocGlobs(projectDir string, res []*regexp.Regexp) ([]string, error) {
+	var files []string
+	err := filepath.Walk(projectDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != projectDir && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if matchesAnyGlob(rel, res) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan project: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func globRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// chunkByHeading splits a document into pieces of at most limit bytes.
+// Sections start at Markdown headings outside code fences and are packed
+// together while they fit; a section that doesn't fit on its own is split
+// between paragraphs.
+func chunkByHeading(text string, limit int) []docChunk {
+	var sections []docChunk
+	var cur docChunk
+	var buf strings.Builder
+	inFence := false
+	flush := func() {
+		if strings.TrimSpace(buf.String()) != "" {
+			cur.Text = buf.String()
+			sections = append(sections, cur)
+		}
+		buf.Reset()
+		cur = docChunk{}
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && isMarkdownHeading(trimmed) {
+			flush()
+			cur.Heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		}
+		buf.WriteString(line)
+	}
+	flush()
+
+	var chunks []docChunk
+	for _, sec := range sections {
+		if len(sec.Text) > limit {
+			for i, part := range splitParagraphs(sec.Text, limit) {
+				heading := sec.Heading
+				if i > 0 && heading != "" {
+					heading += " (cont.)"
+				}
+				chunks = append(chunks, docChunk{Heading: heading, Text: part})
+			}
+			continue
+		}
+		if n := len(chunks); n > 0 && len(chunks[n-1].Text)+len(sec.Text) <= limit {
+			chunks[n-1].Text += sec.Text
+			continue
+		}
+		chunks = append(chunks, sec)
+	}
+	for i := range chunks {
+		chunks[i].Text = strings.TrimSpace(chunks[i].Text)
+	}
+	return chunks
+}
+
+func isMarkdownHeading(line string) bool {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	return level >= 1 && level <= 6 && len(line) > level && line[level] == ' '
+}
+
+// splitParagraphs packs blank-line separated paragraphs into pieces of at
+// most limit bytes, cutting oversized paragraphs at line breaks, and lines
+// that are still too long at a rune boundary.
+func splitParagraphs(text string, limit int) []string {
+	var parts []string
+	var buf strings.Builder
+	add := func(s string) {
+		if buf.Len() > 0 && buf.Len()+len(s) > limit {
+			parts = append(parts, buf.String())
+			buf.Reset()
+		}
+		buf.WriteString(s)
+	}
+	for _, para := range strings.SplitAfter(text, "\n\n") {
+		if len(para) <= limit {
+			add(para)
+			continue
+		}
+		for _, line := range strings.SplitAfter(para, "\n") {
+			for len(line) > limit {
+				cut := limit
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				if cut == 0 {
+					cut = limit
+				}
+				add(line[:cut])
+				line = line[cut:]
+			}
+			add(line)
+		}
+	}
+	if buf.Len() > 0 {
+		parts = append(parts, buf.String())
+	}
+	return parts
+}
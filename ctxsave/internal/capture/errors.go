@@ -0,0 +1,146 @@
+package capture
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+var resolutionKeywords = []string{
+	"fixed", "resolved", "that fixes", "this fixes", "now passes", "passes now",
+	"works now", "now works", "should work now", "is now working", "no more errors",
+	"compiles now", "builds now", "build passes",
+}
+
+// resolutionKeywordRe matches the keywords as whole words, so "prefixed"
+// and "unfixed" don't count.
+var resolutionKeywordRe = func() *regexp.Regexp {
+	quoted := make([]string, len(resolutionKeywords))
+	for i, kw := range resolutionKeywords {
+		quoted[i] = regexp.QuoteMeta(kw)
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}()
+
+// errorMeta is the metadata linkErrorResolutions adds to EntryError entries.
+// Status is "open" until a later edit or assistant message in the same
+// transcript resolves it, and "repeated" when the error comes back. An edit
+// to a file the error doesn't mention only counts as a "possible_edit"
+// resolution, and only when nothing more specific follows.
+type errorMeta struct {
+	Source          string `json:"source"`
+	Status          string `json:"status"`
+	Resolution      string `json:"resolution,omitempty"`
+	ResolvedBy      string `json:"resolved_by,omitempty"`
+	ResolvedByOrder *int   `json:"resolved_by_order,omitempty"`
+}
+
+// linkErrorResolutions walks the turns after each error and records the edit
+// or assistant message that fixed it. An error that shows up again later in
+// the transcript, before or after an attempted fix, is left to its later
+// occurrence, so only the last attempt is paired and a fix that didn't hold
+// is never reported as one.
+func linkErrorResolutions(entries []parsedEntry) {
+	for i := range entries {
+		if entries[i].Type != store.EntryError {
+			continue
+		}
+
+		merged := make(map[string]any)
+		if entries[i].Meta != "" {
+			_ = json.Unmarshal([]byte(entries[i].Meta), &merged)
+		}
+		meta := errorMeta{Source: "tool_result", Status: "open"}
+		if src, ok := merged["source"].(string); ok && src != "" {
+			meta.Source = src
+		}
+
+		signature := firstErrorLine(entries[i].Content)
+		resolved, possible := false, false
+		for j := i + 1; j < len(entries); j++ {
+			next := entries[j]
+
+			if next.Type == store.EntryError {
+				if firstErrorLine(next.Content) == signature {
+					meta = errorMeta{Source: meta.Source, Status: "repeated"}
+					break
+				}
+				continue
+			}
+
+			if resolved {
+				continue
+			}
+			kind, by := resolutionOf(next, entries[i].Content)
+			if kind == "" || (kind == "possible_edit" && possible) {
+				continue
+			}
+			order := j
+			meta.Status = "resolved"
+			meta.Resolution = kind
+			meta.ResolvedBy = by
+			meta.ResolvedByOrder = &order
+			resolved = kind != "possible_edit"
+			possible = true
+		}
+
+		// keep whatever else the parser recorded, e.g. the tool name
+		var fields map[string]any
+		data, _ := json.Marshal(meta)
+		_ = json.Unmarshal(data, &fields)
+		for k, v := range fields {
+			merged[k] = v
+		}
+		data, _ = json.Marshal(merged)
+		entries[i].Meta = string(data)
+	}
+}
+
+// resolutionOf reports whether an entry looks like it fixed the preceding
+// error errText. An edit is an "edit" resolution when the error names the
+// edited file, and a "possible_edit" otherwise.
+func resolutionOf(pe parsedEntry, errText string) (string, string) {
+	switch pe.Type {
+	case store.EntryCodeChange:
+		for prefix, verb := range map[string]string{"Edited ": "editing ", "Wrote ": "writing "} {
+			if !strings.HasPrefix(pe.Content, prefix) {
+				continue
+			}
+			path := strings.TrimPrefix(pe.Content, prefix)
+			if mentionsFile(errText, path) {
+				return "edit", verb + path
+			}
+			return "possible_edit", verb + path
+		}
+	case store.EntryConversation, store.EntryDecision:
+		if !strings.Contains(pe.Meta, `"role":"assistant"`) {
+			return "", ""
+		}
+		if resolutionKeywordRe.MatchString(pe.Content) {
+			return "message", truncate(firstErrorLine(pe.Content), 150)
+		}
+	}
+	return "", ""
+}
+
+// mentionsFile reports whether text names path, in full or by file name.
+func mentionsFile(text, path string) bool {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return false
+	}
+	return strings.Contains(text, path) || strings.Contains(text, filepath.Base(filepath.FromSlash(path)))
+}
+
+func firstErrorLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
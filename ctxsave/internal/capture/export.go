@@ -0,0 +1,376 @@
+package capture
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ctxsave/internal/store"
+)
+
+// ExportConversation is one conversation from a ChatGPT or Claude.ai data export.
+type ExportConversation struct {
+	ID        string
+	Title     string
+	Source    string // "chatgpt" or "claude.ai"
+	UpdatedAt time.Time
+	Messages  []ExportMessage
+}
+
+type ExportMessage struct {
+	Role string // "user" or "assistant"
+	Text string
+}
+
+type chatGPTConversation struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	UpdateTime  float64 `json:"update_time"`
+	CurrentNode string  `json:"current_node"`
+	Mapping     map[string]struct {
+		Parent  string `json:"parent"`
+		Message *struct {
+			Author struct {
+				Role string `json:"role"`
+			} `json:"author"`
+			Content struct {
+				ContentType string            `json:"content_type"`
+				Parts       []json.RawMessage `json:"parts"`
+			} `json:"content"`
+		} `json:"message"`
+	} `json:"mapping"`
+}
+
+type claudeConversation struct {
+	UUID         string `json:"uuid"`
+	Name         string `json:"name"`
+	UpdatedAt    string `json:"updated_at"`
+	ChatMessages []struct {
+		Sender  string `json:"sender"`
+		Text    string `json:"text"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"chat_messages"`
+}
+
+// LoadExport reads conversations.json from a ChatGPT or Claude.ai export,
+// either directly or from inside the export zip.
+func LoadExport(path string) ([]ExportConversation, error) {
+	data, err := readExportFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("parse conversations.json: %w", err)
+	}
+	if len(probe) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := probe[0]["mapping"]; ok {
+		return parseChatGPTExport(data)
+	}
+	if _, ok := probe[0]["chat_messages"]; ok {
+		return parseClaudeExport(data)
+	}
+	return nil, fmt.Errorf("unrecognized export format — expected a ChatGPT or Claude.ai conversations.json")
+}
+
+func readExportFile(path string) ([]byte, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read export: %w", err)
+		}
+		return data, nil
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open export zip: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) != "conversations.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Name, err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("no conversations.json in %s", filepath.Base(path))
+}
+
+func parseChatGPTExport(data []byte) ([]ExportConversation, error) {
+	var raw []chatGPTConversation
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse ChatGPT export: %w", err)
+	}
+
+	var convs []ExportConversation
+	for _, rc := range raw {
+		conv := ExportConversation{
+			ID:        rc.ID,
+			Title:     rc.Title,
+			Source:    "chatgpt",
+			UpdatedAt: time.Unix(int64(rc.UpdateTime), 0).UTC(),
+		}
+
+		// The mapping is a tree of edits and regenerations; the branch the
+		// user last saw runs from current_node back to the root.
+		var branch []ExportMessage
+		for id := rc.CurrentNode; id != ""; id = rc.Mapping[id].Parent {
+			node, ok := rc.Mapping[id]
+			if !ok {
+				break
+			}
+			if node.Message == nil || node.Message.Content.ContentType != "text" {
+				continue
+			}
+			role := node.Message.Author.Role
+			if role != "user" && role != "assistant" {
+				continue
+			}
+			var parts []string
+			for _, p := range node.Message.Content.Parts {
+				var s string
+				if json.Unmarshal(p, &s) == nil && s != "" {
+					parts = append(parts, s)
+				}
+			}
+			if len(parts) > 0 {
+				branch = append(branch, ExportMessage{Role: role, Text: strings.Join(parts, "\n")})
+			}
+		}
+		for i := len(branch) - 1; i >= 0; i-- {
+			conv.Messages = append(conv.Messages, branch[i])
+		}
+
+		convs = append(convs, conv)
+	}
+	return convs, nil
+}
+
+func parseClaudeExport(data []byte) ([]ExportConversation, error) {
+	var raw []claudeConversation
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse Claude.ai export: %w", err)
+	}
+
+	var convs []ExportConversation
+	for _, rc := range raw {
+		updated, _ := time.Parse(time.RFC3339, rc.UpdatedAt)
+		conv := ExportConversation{
+			ID:        rc.UUID,
+			Title:     rc.Name,
+			Source:    "claude.ai",
+			UpdatedAt: updated,
+		}
+		for _, m := range rc.ChatMessages {
+			role := "assistant"
+			if m.Sender == "human" {
+				role = "user"
+			}
+			text := m.Text
+			if text == "" {
+				var parts []string
+				for _, c := range m.Content {
+					if c.Type == "text" && c.Text != "" {
+						parts = append(parts, c.Text)
+					}
+				}
+				text = strings.Join(parts, "\n")
+			}
+			if text != "" {
+				conv.Messages = append(conv.Messages, ExportMessage{Role: role, Text: text})
+			}
+		}
+		convs = append(convs, conv)
+	}
+	return convs, nil
+}
+
+// FilterRelevantConversations keeps conversations that mention the
+// project's absolute path, or that mention the project name together with
+// one of the keywords or a file path from the project tree. The name or a
+// relative path like "cmd/" alone is too common to go by. Results are
+// sorted newest first.
+func FilterRelevantConversations(convs []ExportConversation, projectDir, project string, keywords []string) []ExportConversation {
+	var words []*regexp.Regexp
+	for _, kw := range keywords {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			words = append(words, wordPattern(kw))
+		}
+	}
+	var name *regexp.Regexp
+	if len(project) >= 3 {
+		name = wordPattern(project)
+	}
+	root := filepath.ToSlash(filepath.Clean(projectDir))
+	paths := projectPaths(projectDir)
+
+	var relevant []ExportConversation
+	for _, c := range convs {
+		text := c.Title + "\n" + c.text()
+		switch {
+		case root != "/" && strings.Contains(filepath.ToSlash(text), root):
+		case name != nil && name.MatchString(text) &&
+			(matchesAnyWord(text, words) || containsAny(strings.ToLower(text), paths)):
+		default:
+			continue
+		}
+		relevant = append(relevant, c)
+	}
+
+	sort.SliceStable(relevant, func(i, j int) bool {
+		return relevant[i].UpdatedAt.After(relevant[j].UpdatedAt)
+	})
+	return relevant
+}
+
+func wordPattern(word string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
+}
+
+func (c ExportConversation) text() string {
+	var sb strings.Builder
+	for _, m := range c.Messages {
+		sb.WriteString(m.Text)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// projectPaths returns lowercased relative paths with at least one
+// directory component, which are specific enough to identify the project
+// when they show up in a chat.
+func projectPaths(projectDir string) []string {
+	var paths []string
+	_ = filepath.Walk(projectDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != projectDir && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil || !strings.Contains(rel, string(filepath.Separator)) {
+			return nil
+		}
+		paths = append(paths, strings.ToLower(filepath.ToSlash(rel)))
+		if len(paths) >= 5000 {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return paths
+}
+
+func matchesAnyWord(s string, words []*regexp.Regexp) bool {
+	for _, re := range words {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, needles []string) bool {
+	for _, n := range needles {
+		if n != "" && strings.Contains(s, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// CaptureExportConversation stores one exported conversation as a session.
+// Conversations are keyed by source and id, so importing a newer export of
+// the same account skips the ones already captured. A conversation that has
+// gained messages since is captured again and replaces its old session.
+func CaptureExportConversation(st *store.Store, conv ExportConversation, project string) (*store.Session, bool, error) {
+	key := fmt.Sprintf("%s:%s", conv.Source, conv.ID)
+	oldSession, count, found, err := st.ProcessedTranscript(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if found && count >= int64(len(conv.Messages)) {
+		return nil, true, nil
+	}
+
+	var entries []parsedEntry
+	for _, m := range conv.Messages {
+		entries = append(entries, messageEntries(m.Role, m.Text)...)
+	}
+	if len(entries) == 0 {
+		return nil, false, fmt.Errorf("no messages in %q", conv.Title)
+	}
+
+	label := conv.Title
+	if label == "" {
+		label = conv.ID
+	}
+	sess, err := st.CreateSession(conv.Source, project, label)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := st.SetSessionOrigin(sess.ID, key); err != nil {
+		return nil, false, err
+	}
+	if err := storeParsedEntries(st, sess.ID, entries); err != nil {
+		return nil, false, err
+	}
+	if err := st.MarkTranscriptProcessed(key, sess.ID, int64(len(conv.Messages))); err != nil {
+		return nil, false, err
+	}
+	if found {
+		if err := replaceSession(st, oldSession, sess.ID); err != nil {
+			return nil, false, fmt.Errorf("replace earlier import: %w", err)
+		}
+	}
+	return sess, false, nil
+}
+
+// replaceSession moves the session-level tags and pin of an earlier import
+// to its replacement and deletes the earlier one.
+func replaceSession(st *store.Store, oldID, newID string) error {
+	old, err := st.GetSession(oldID)
+	if err != nil {
+		// deleted by hand since
+		return nil
+	}
+	tags, err := st.SessionTags(oldID)
+	if err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		if err := st.TagSession(newID, tags...); err != nil {
+			return err
+		}
+	}
+	if old.Pinned {
+		if err := st.SetSessionPin(newID, true, old.Priority); err != nil {
+			return err
+		}
+	}
+	return st.DeleteSession(oldID)
+}
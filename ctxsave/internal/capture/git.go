@@ -1,8 +1,11 @@
 package capture
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"ctxsave/internal/store"
@@ -16,7 +19,7 @@ func CaptureFromGit(st *store.Store, projectDir, project string, since string, m
 	if maxCommits > 0 {
 		args = append(args, fmt.Sprintf("-n%d", maxCommits))
 	}
-	args = append(args, "--format=%H|||%s|||%an|||%ai")
+	args = append(args, commitFormat)
 
 	cmd := exec.Command("git", args...)
 	cmd.Dir = projectDir
@@ -41,37 +44,208 @@ func CaptureFromGit(st *store.Store, projectDir, project string, since string, m
 
 	lines := strings.Split(logOutput, "\n")
 	for i, line := range lines {
-		parts := strings.SplitN(line, "|||", 4)
-		if len(parts) < 4 {
+		e, _, ok := commitEntry(line)
+		if !ok {
 			continue
 		}
-		hash, subject, author, date := parts[0], parts[1], parts[2], parts[3]
-
-		content := fmt.Sprintf("[%s] %s (by %s, %s)", hash[:8], subject, author, date)
-		meta := fmt.Sprintf(`{"hash":"%s","author":"%s","date":"%s"}`, hash, author, date)
-
-		if _, err := st.AddEntry(sess.ID, store.EntryGitCommit, content, meta, i); err != nil {
+		if _, err := st.AddEntry(sess.ID, e.Type, e.Content, e.Metadata, i); err != nil {
 			return nil, err
 		}
 	}
 
+	// the entries after the commits are numbered in the order they're added
+	next := len(lines)
+
 	diffCmd := exec.Command("git", "diff", "--stat")
 	diffCmd.Dir = projectDir
 	diffOut, err := diffCmd.Output()
 	if err == nil && len(strings.TrimSpace(string(diffOut))) > 0 {
-		if _, err := st.AddEntry(sess.ID, store.EntryGitDiff, truncate(string(diffOut), 3000), `{"type":"unstaged"}`, len(lines)); err != nil {
+		if _, err := st.AddEntry(sess.ID, store.EntryGitDiff, truncate(string(diffOut), 3000), `{"type":"unstaged"}`, next); err != nil {
 			return nil, err
 		}
+		next++
 	}
 
 	stagedCmd := exec.Command("git", "diff", "--staged", "--stat")
 	stagedCmd.Dir = projectDir
 	stagedOut, err := stagedCmd.Output()
 	if err == nil && len(strings.TrimSpace(string(stagedOut))) > 0 {
-		if _, err := st.AddEntry(sess.ID, store.EntryGitDiff, truncate(string(stagedOut), 3000), `{"type":"staged"}`, len(lines)+1); err != nil {
+		if _, err := st.AddEntry(sess.ID, store.EntryGitDiff, truncate(string(stagedOut), 3000), `{"type":"staged"}`, next); err != nil {
+			return nil, err
+		}
+		next++
+	}
+
+	logArgs := []string{"log", "-p", "-U0", "--no-color", "--format="}
+	if since != "" {
+		logArgs = append(logArgs, "--since", since)
+	}
+	if maxCommits > 0 {
+		logArgs = append(logArgs, fmt.Sprintf("-n%d", maxCommits))
+	}
+	// diff paths are relative to the repository root, not the project
+	root := projectDir
+	if top, err := gitOutput(projectDir, "rev-parse", "--show-toplevel"); err == nil && strings.TrimSpace(top) != "" {
+		root = strings.TrimSpace(top)
+	}
+	if err := resolveStoredTodos(st); err != nil {
+		return nil, err
+	}
+	todos := introducedTodos(projectDir, root, logArgs)
+	todos = append(todos, introducedTodos(projectDir, root, []string{"diff", "-U0", "--no-color", "HEAD"})...)
+	if len(todos) > 0 {
+		meta, _ := json.Marshal(map[string]string{"type": "todos", "root": root})
+		if _, err := st.AddEntry(sess.ID, store.EntryGitDiff, truncate(strings.Join(todos, "\n"), 3000), string(meta), next); err != nil {
+			return nil, err
+		}
+		next++
+	}
+
+	if hot, err := AnalyzeHotspots(projectDir, since); err == nil && hot != nil {
+		if _, err := st.AddEntry(sess.ID, store.EntryGitDiff, hot.Render(), hot.metadata(), next); err != nil {
+			return nil, err
+		}
+		next++
+	}
+
+	if state, meta := workingState(projectDir); state != "" {
+		if _, err := st.AddEntry(sess.ID, store.EntryGitDiff, state, meta, next); err != nil {
 			return nil, err
 		}
 	}
 
 	return sess, nil
 }
+
+// introducedTodos runs a git diff-producing command and returns the TODO and
+// FIXME lines it adds that are still in the working tree, as "path: line"
+// with the path relative to root.
+func introducedTodos(projectDir, root string, args []string) []string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = projectDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	// a TODO added in history and resolved since is no longer open
+	tree := newTreeFiles(root)
+	seen := make(map[string]bool)
+	var todos []string
+	file := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			continue
+		}
+		if !strings.HasPrefix(line, "+") {
+			continue
+		}
+		added := strings.TrimSpace(line[1:])
+		if !strings.Contains(added, "TODO") && !strings.Contains(added, "FIXME") {
+			continue
+		}
+		item := fmt.Sprintf("%s: %s", file, truncate(added, 200))
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		if tree.has(file, added) {
+			todos = append(todos, item)
+		}
+	}
+	return todos
+}
+
+// resolveStoredTodos checks the TODOs recorded by earlier captures against
+// the working tree. Lines no longer there are listed under "resolved" in
+// the entry's metadata, and an entry with none left open is marked
+// resolved, so briefings stop listing them.
+func resolveStoredTodos(st *store.Store) error {
+	diffs, err := st.GetEntriesByType(store.EntryGitDiff)
+	if err != nil {
+		return err
+	}
+	trees := make(map[string]*treeFiles)
+	for _, e := range diffs {
+		var meta map[string]any
+		if json.Unmarshal([]byte(e.Metadata), &meta) != nil || meta["type"] != "todos" || meta["status"] == "resolved" {
+			continue
+		}
+		root, _ := meta["root"].(string)
+		if root == "" {
+			continue
+		}
+		if trees[root] == nil {
+			trees[root] = newTreeFiles(root)
+		}
+
+		var resolved []string
+		done := make(map[string]bool)
+		if list, ok := meta["resolved"].([]any); ok {
+			for _, r := range list {
+				if s, ok := r.(string); ok {
+					resolved = append(resolved, s)
+					done[s] = true
+				}
+			}
+		}
+		changed, open := false, 0
+		for _, todo := range strings.Split(e.Content, "\n") {
+			if todo == "" || done[todo] {
+				continue
+			}
+			path, text, ok := strings.Cut(todo, ": ")
+			if !ok || trees[root].has(path, text) {
+				open++
+				continue
+			}
+			resolved = append(resolved, todo)
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		meta["resolved"] = resolved
+		if open == 0 {
+			meta["status"] = "resolved"
+		}
+		data, _ := json.Marshal(meta)
+		if err := st.UpdateEntryMetadata(e.ID, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// treeFiles reads working-tree files under root on demand to check whether
+// a TODO line is still there.
+type treeFiles struct {
+	root  string
+	lines map[string][]string
+}
+
+func newTreeFiles(root string) *treeFiles {
+	return &treeFiles{root: root, lines: make(map[string][]string)}
+}
+
+// has reports whether file still contains the line. Lines cut short at
+// capture end in "..." and match by prefix.
+func (t *treeFiles) has(file, line string) bool {
+	lines, ok := t.lines[file]
+	if !ok {
+		if data, err := os.ReadFile(filepath.Join(t.root, filepath.FromSlash(file))); err == nil {
+			for _, l := range strings.Split(string(data), "\n") {
+				lines = append(lines, strings.TrimSpace(l))
+			}
+		}
+		t.lines[file] = lines
+	}
+	prefix := strings.TrimSuffix(line, "...")
+	for _, l := range lines {
+		if l == line || (prefix != line && strings.HasPrefix(l, prefix)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package capture
+
+import (
+	"fmt"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+const commitFormat = "--format=%H|||%s|||%an|||%ai"
+
+// CaptureGitEvent records what a git hook saw: the new commit after
+// post-commit, the merged commits after post-merge, and the branch switch
+// after post-checkout. Each event also refreshes the working state.
+// Commits already in the store are skipped, so hooks and 'capture git' can
+// be mixed freely.
+func CaptureGitEvent(st *store.Store, projectDir, project, hook string, args []string) (*store.Session, error) {
+	var revs []string
+	var label string
+	var extra []store.Entry
+
+	switch hook {
+	case "post-commit":
+		revs = []string{"-n1", "HEAD"}
+	case "post-merge":
+		revs = []string{"ORIG_HEAD..HEAD"}
+	case "post-checkout":
+		// args: previous HEAD, new HEAD, 1 for a branch checkout (0 for files)
+		if len(args) < 3 || args[2] != "1" {
+			return nil, nil
+		}
+		from, to := args[0], args[1]
+		if reflog, err := gitOutput(projectDir, "reflog", "-1", "--format=%gs"); err == nil {
+			if _, moved, ok := strings.Cut(strings.TrimSpace(reflog), "checkout: moving from "); ok {
+				if f, t, ok := strings.Cut(moved, " to "); ok {
+					from, to = f, t
+				}
+			}
+		}
+		if from == to {
+			return nil, nil
+		}
+		label = fmt.Sprintf("checkout %s", to)
+		extra = append(extra, store.Entry{
+			Type:     store.EntryGitDiff,
+			Content:  fmt.Sprintf("Switched branch from %s to %s", from, to),
+			Metadata: fmt.Sprintf(`{"type":"checkout","from":%q,"to":%q}`, from, to),
+		})
+	default:
+		return nil, fmt.Errorf("unknown hook %q — expected one of %s", hook, strings.Join(GitHookNames, ", "))
+	}
+
+	var commits []store.Entry
+	if revs != nil {
+		out, err := gitOutput(projectDir, append([]string{"log", commitFormat}, revs...)...)
+		if err != nil {
+			return nil, fmt.Errorf("git log: %w", err)
+		}
+		known, err := storedCommits(st)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range nonEmptyLines(out) {
+			e, hash, ok := commitEntry(line)
+			if !ok || known[hash] {
+				continue
+			}
+			if stat, err := gitOutput(projectDir, "show", "--stat", "--format=", hash); err == nil {
+				if stat = strings.TrimSpace(stat); stat != "" {
+					e.Content += "\n" + truncate(stat, 1500)
+				}
+			}
+			commits = append(commits, e)
+		}
+		if len(commits) == 0 {
+			return nil, nil
+		}
+		if hook == "post-commit" {
+			label = "commit " + commits[0].Content[1:9]
+		} else {
+			label = fmt.Sprintf("merge (%d new commits)", len(commits))
+		}
+	}
+
+	sess, err := st.CreateSession("git-hook", project, label)
+	if err != nil {
+		return nil, err
+	}
+	entries := append(commits, extra...)
+	if state, meta := workingState(projectDir); state != "" {
+		entries = append(entries, store.Entry{Type: store.EntryGitDiff, Content: state, Metadata: meta})
+	}
+	for i, e := range entries {
+		if _, err := st.AddEntry(sess.ID, e.Type, e.Content, e.Metadata, i); err != nil {
+			return nil, err
+		}
+	}
+	return sess, nil
+}
+
+// commitEntry turns a commitFormat log line into a commit entry.
+func commitEntry(line string) (store.Entry, string, bool) {
+	parts := strings.SplitN(line, "|||", 4)
+	if len(parts) < 4 {
+		return store.Entry{}, "", false
+	}
+	hash, subject, author, date := parts[0], parts[1], parts[2], parts[3]
+	return store.Entry{
+		Type:     store.EntryGitCommit,
+		Content:  fmt.Sprintf("[%s] %s (by %s, %s)", hash[:8], subject, author, date),
+		Metadata: fmt.Sprintf(`{"hash":"%s","author":"%s","date":"%s"}`, hash, author, date),
+	}, hash, true
+}
+
+func storedCommits(st *store.Store) (map[string]bool, error) {
+	entries, err := st.GetEntriesByType(store.EntryGitCommit)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, e := range entries {
+		if _, rest, ok := strings.Cut(e.Metadata, `"hash":"`); ok {
+			if hash, _, ok := strings.Cut(rest, `"`); ok {
+				known[hash] = true
+			}
+		}
+	}
+	return known, nil
+}
@@ -0,0 +1,167 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const gitHookMarker = "# ctxsave git hook"
+
+// GitHookNames are the hooks ctxsave installs.
+var GitHookNames = []string{"post-commit", "post-checkout", "post-merge"}
+
+// chainedSuffix is appended to a hook that existed before ctxsave's, which
+// then runs it first.
+const chainedSuffix = ".pre-ctxsave"
+
+const gitHookTemplate = `#!/bin/sh
+%s — records commits, merges and branch switches
+hookdir=$(dirname "$0")
+status=0
+if [ -x "$hookdir/%[2]s%[3]s" ]; then
+	"$hookdir/%[2]s%[3]s" "$@" || status=$?
+fi
+ctxsave=%[4]s
+command -v "$ctxsave" >/dev/null 2>&1 || ctxsave=ctxsave
+(cd %[5]s && "$ctxsave" hooks run %[2]s "$@") >/dev/null 2>&1 || true
+exit $status
+`
+
+// GitHooksDir returns the directory git runs hooks from, honoring
+// core.hooksPath.
+func GitHooksDir(projectDir string) (string, error) {
+	if out, err := gitOutput(projectDir, "config", "core.hooksPath"); err == nil {
+		if p := strings.TrimSpace(out); p != "" {
+			if strings.HasPrefix(p, "~/") {
+				home, _ := os.UserHomeDir()
+				p = filepath.Join(home, p[2:])
+			}
+			if !filepath.IsAbs(p) {
+				top, err := gitOutput(projectDir, "rev-parse", "--show-toplevel")
+				if err != nil {
+					return "", fmt.Errorf("find repository root: %w", err)
+				}
+				p = filepath.Join(strings.TrimSpace(top), p)
+			}
+			return p, nil
+		}
+	}
+
+	out, err := gitOutput(projectDir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository")
+	}
+	gitDir := strings.TrimSpace(out)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(projectDir, gitDir)
+	}
+	return filepath.Join(gitDir, "hooks"), nil
+}
+
+// InstallGitHooks writes ctxsave's hooks into the hooks directory. A hook
+// that is already there is kept under a .pre-ctxsave name and run first.
+// Installing again only refreshes ctxsave's scripts. A core.hooksPath
+// outside the repository is refused: the hooks record this project, and a
+// shared directory would run them for every other repository too.
+func InstallGitHooks(projectDir, executable string) (string, error) {
+	hooksDir, err := GitHooksDir(projectDir)
+	if err != nil {
+		return "", err
+	}
+	inside, err := insideRepository(projectDir, hooksDir)
+	if err != nil {
+		return "", err
+	}
+	if !inside {
+		return "", fmt.Errorf("core.hooksPath points at %s, outside this repository — hooks there run for every repository using it; set a repository-local core.hooksPath or unset it first", hooksDir)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", fmt.Errorf("create %s: %w", hooksDir, err)
+	}
+
+	for _, name := range GitHookNames {
+		path := filepath.Join(hooksDir, name)
+		if data, err := os.ReadFile(path); err == nil && !strings.Contains(string(data), gitHookMarker) {
+			if _, err := os.Stat(path + chainedSuffix); err == nil {
+				return "", fmt.Errorf("%s and %s both exist — merge them by hand first", name, name+chainedSuffix)
+			}
+			if err := os.Rename(path, path+chainedSuffix); err != nil {
+				return "", fmt.Errorf("keep existing %s: %w", name, err)
+			}
+		}
+
+		script := fmt.Sprintf(gitHookTemplate, gitHookMarker, name, chainedSuffix, shellQuote(executable), shellQuote(projectDir))
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			return "", fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return hooksDir, nil
+}
+
+// insideRepository reports whether dir is within the repository's working
+// tree or its git directory.
+func insideRepository(projectDir, dir string) (bool, error) {
+	out, err := gitOutput(projectDir, "rev-parse", "--show-toplevel", "--git-common-dir")
+	if err != nil {
+		return false, fmt.Errorf("find repository root: %w", err)
+	}
+	dir = resolvePath(dir)
+	for _, root := range strings.Split(strings.TrimSpace(out), "\n") {
+		root = strings.TrimSpace(root)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(projectDir, root)
+		}
+		rel, err := filepath.Rel(resolvePath(root), dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// resolvePath follows symlinks in p as far as it exists, so paths through
+// a symlinked directory compare equal to their targets.
+func resolvePath(p string) string {
+	p = filepath.Clean(p)
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	if parent := filepath.Dir(p); parent != p {
+		return filepath.Join(resolvePath(parent), filepath.Base(p))
+	}
+	return p
+}
+
+// UninstallGitHooks removes ctxsave's hooks and puts back the hooks they
+// were chained to.
+func UninstallGitHooks(projectDir string) (string, int, error) {
+	hooksDir, err := GitHooksDir(projectDir)
+	if err != nil {
+		return "", 0, err
+	}
+
+	removed := 0
+	for _, name := range GitHookNames {
+		path := filepath.Join(hooksDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil || !strings.Contains(string(data), gitHookMarker) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return "", removed, fmt.Errorf("remove %s: %w", name, err)
+		}
+		removed++
+		if _, err := os.Stat(path + chainedSuffix); err == nil {
+			if err := os.Rename(path+chainedSuffix, path); err != nil {
+				return "", removed, fmt.Errorf("restore %s: %w", name, err)
+			}
+		}
+	}
+	return hooksDir, removed, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,142 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const maxUntrackedListed = 20
+
+type gitState struct {
+	Branch    string `json:"branch"`
+	Upstream  string `json:"upstream,omitempty"`
+	Ahead     int    `json:"ahead"`
+	Behind    int    `json:"behind"`
+	Operation string `json:"operation,omitempty"`
+	Stashes   int    `json:"stashes"`
+	Untracked int    `json:"untracked"`
+}
+
+// CurrentBranch returns the checked-out branch of the repository containing
+// dir, or "" outside a repository and on a detached HEAD.
+func CurrentBranch(dir string) string {
+	out, err := gitOutput(dir, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// workingState describes the branch, its upstream, any merge or rebase in
+// progress, stashes and untracked files, one fact per line.
+func workingState(projectDir string) (string, string) {
+	st := gitState{Branch: CurrentBranch(projectDir)}
+
+	var lines []string
+	branchLine := "Branch: " + st.Branch
+	if st.Branch == "" {
+		head, _ := gitOutput(projectDir, "rev-parse", "--short", "HEAD")
+		branchLine = "Branch: detached HEAD at " + strings.TrimSpace(head)
+	}
+	if up, err := gitOutput(projectDir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"); err == nil {
+		st.Upstream = strings.TrimSpace(up)
+		if counts, err := gitOutput(projectDir, "rev-list", "--left-right", "--count", "HEAD...@{u}"); err == nil {
+			if f := strings.Fields(counts); len(f) == 2 {
+				st.Ahead, _ = strconv.Atoi(f[0])
+				st.Behind, _ = strconv.Atoi(f[1])
+			}
+		}
+		branchLine += fmt.Sprintf(" → %s (%d ahead, %d behind)", st.Upstream, st.Ahead, st.Behind)
+	}
+	lines = append(lines, branchLine)
+
+	st.Operation = inProgressOperation(projectDir)
+	if st.Operation != "" {
+		lines = append(lines, fmt.Sprintf("In progress: %s", st.Operation))
+	}
+
+	if out, err := gitOutput(projectDir, "stash", "list", "--format=%gd: %s"); err == nil {
+		stashes := nonEmptyLines(out)
+		st.Stashes = len(stashes)
+		if len(stashes) > 0 {
+			lines = append(lines, "Stashes: "+truncate(strings.Join(stashes, "; "), 500))
+		}
+	}
+
+	if out, err := gitOutput(projectDir, "ls-files", "--others", "--exclude-standard"); err == nil {
+		var untracked []string
+		for _, f := range nonEmptyLines(out) {
+			if !strings.HasPrefix(f, ".ctxsave/") {
+				untracked = append(untracked, f)
+			}
+		}
+		st.Untracked = len(untracked)
+		if len(untracked) > 0 {
+			shown := untracked
+			if len(shown) > maxUntrackedListed {
+				shown = shown[:maxUntrackedListed]
+			}
+			line := "Untracked: " + strings.Join(shown, ", ")
+			if extra := len(untracked) - len(shown); extra > 0 {
+				line += fmt.Sprintf(" (+%d more)", extra)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	meta, _ := json.Marshal(struct {
+		Type string `json:"type"`
+		gitState
+	}{"state", st})
+	return strings.Join(lines, "\n"), string(meta)
+}
+
+// inProgressOperation reports a merge, rebase, cherry-pick, revert or
+// bisect that was started but not finished.
+func inProgressOperation(projectDir string) string {
+	out, err := gitOutput(projectDir, "rev-parse", "--git-dir")
+	if err != nil {
+		return ""
+	}
+	gitDir := strings.TrimSpace(out)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(projectDir, gitDir)
+	}
+
+	markers := []struct{ path, op string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+		{"BISECT_LOG", "bisect"},
+	}
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(gitDir, m.path)); err == nil {
+			return m.op
+		}
+	}
+	return ""
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,306 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHotspotWindow is how far back churn is measured when capture git
+	// isn't given --since.
+	DefaultHotspotWindow = "90 days ago"
+
+	maxHotFiles   = 8
+	maxHotDirs    = 5
+	maxClusters   = 4
+	maxAuthors    = 5
+	maxCommitSize = 30 // bigger commits (renames, formatting sweeps) say nothing about coupling
+)
+
+type fileChurn struct {
+	Path    string
+	Commits int
+	Added   int
+	Deleted int
+}
+
+type authorActivity struct {
+	Name    string
+	Commits int
+	Last    time.Time
+}
+
+// Hotspots summarizes where work has concentrated over a window of history.
+type Hotspots struct {
+	Window   string
+	Commits  int
+	Files    []fileChurn
+	Dirs     []fileChurn
+	Clusters [][]string
+	Authors  []authorActivity
+}
+
+type numstatCommit struct {
+	Author string
+	Time   time.Time
+	Files  []fileChurn
+}
+
+// AnalyzeHotspots reads git log --numstat since the given window and ranks
+// files and directories by churn, groups files that keep changing together
+// and lists the recently active authors.
+func AnalyzeHotspots(projectDir, since string) (*Hotspots, error) {
+	if since == "" {
+		since = DefaultHotspotWindow
+	}
+	out, err := gitOutput(projectDir, "log", "--numstat", "--no-merges", "--no-renames",
+		"--since", since, "--format=%x00%an|%at")
+	if err != nil {
+		return nil, fmt.Errorf("git log --numstat: %w", err)
+	}
+	commits := parseNumstat(out)
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	h := &Hotspots{Window: since, Commits: len(commits)}
+	files := make(map[string]*fileChurn)
+	dirs := make(map[string]*fileChurn)
+	authors := make(map[string]*authorActivity)
+	pairs := make(map[[2]string]int)
+
+	for _, c := range commits {
+		a := authors[c.Author]
+		if a == nil {
+			a = &authorActivity{Name: c.Author}
+			authors[c.Author] = a
+		}
+		a.Commits++
+		if c.Time.After(a.Last) {
+			a.Last = c.Time
+		}
+
+		touchedDirs := make(map[string]bool)
+		for _, f := range c.Files {
+			fc := files[f.Path]
+			if fc == nil {
+				fc = &fileChurn{Path: f.Path}
+				files[f.Path] = fc
+			}
+			fc.Commits++
+			fc.Added += f.Added
+			fc.Deleted += f.Deleted
+
+			dir := hotspotDir(f.Path)
+			dc := dirs[dir]
+			if dc == nil {
+				dc = &fileChurn{Path: dir}
+				dirs[dir] = dc
+			}
+			if !touchedDirs[dir] {
+				touchedDirs[dir] = true
+				dc.Commits++
+			}
+			dc.Added += f.Added
+			dc.Deleted += f.Deleted
+		}
+
+		if len(c.Files) > 1 && len(c.Files) <= maxCommitSize {
+			for i := 0; i < len(c.Files); i++ {
+				for j := i + 1; j < len(c.Files); j++ {
+					a, b := c.Files[i].Path, c.Files[j].Path
+					if a > b {
+						a, b = b, a
+					}
+					pairs[[2]string{a, b}]++
+				}
+			}
+		}
+	}
+
+	h.Files = rankChurn(files, maxHotFiles)
+	h.Dirs = rankChurn(dirs, maxHotDirs)
+	h.Clusters = coChangeClusters(pairs, files, len(commits), maxClusters)
+
+	for _, a := range authors {
+		h.Authors = append(h.Authors, *a)
+	}
+	sort.Slice(h.Authors, func(i, j int) bool {
+		if h.Authors[i].Commits != h.Authors[j].Commits {
+			return h.Authors[i].Commits > h.Authors[j].Commits
+		}
+		return h.Authors[i].Last.After(h.Authors[j].Last)
+	})
+	if len(h.Authors) > maxAuthors {
+		h.Authors = h.Authors[:maxAuthors]
+	}
+	return h, nil
+}
+
+func parseNumstat(out string) []numstatCommit {
+	var commits []numstatCommit
+	for _, block := range strings.Split(out, "\x00") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		header := strings.SplitN(lines[0], "|", 2)
+		if len(header) < 2 {
+			continue
+		}
+		ts, _ := strconv.ParseInt(header[1], 10, 64)
+		c := numstatCommit{Author: header[0], Time: time.Unix(ts, 0)}
+		for _, line := range lines[1:] {
+			f := strings.SplitN(line, "\t", 3)
+			if len(f) < 3 || f[0] == "-" {
+				continue // binary files carry no line counts
+			}
+			added, _ := strconv.Atoi(f[0])
+			deleted, _ := strconv.Atoi(f[1])
+			c.Files = append(c.Files, fileChurn{Path: f[2], Added: added, Deleted: deleted})
+		}
+		if len(c.Files) > 0 {
+			commits = append(commits, c)
+		}
+	}
+	return commits
+}
+
+// hotspotDir groups files by directory, which is roughly the package or
+// module they belong to.
+func hotspotDir(p string) string {
+	if dir := path.Dir(p); dir != "." {
+		return dir
+	}
+	return "(root)"
+}
+
+func rankChurn(m map[string]*fileChurn, limit int) []fileChurn {
+	var ranked []fileChurn
+	for _, fc := range m {
+		ranked = append(ranked, *fc)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Commits != ranked[j].Commits {
+			return ranked[i].Commits > ranked[j].Commits
+		}
+		if ci, cj := ranked[i].Added+ranked[i].Deleted, ranked[j].Added+ranked[j].Deleted; ci != cj {
+			return ci > cj
+		}
+		return ranked[i].Path < ranked[j].Path
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// coChangeClusters joins files that usually change together into connected
+// groups, strongest first. A pair needs at least two shared commits and a
+// Jaccard overlap of 0.4; files touched by most commits (READMEs,
+// changelogs, go.mod) couple with everything and are left out.
+func coChangeClusters(pairs map[[2]string]int, files map[string]*fileChurn, commits, limit int) [][]string {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(x string) string {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+
+	weight := make(map[string]int)
+	ubiquitous := func(f string) bool {
+		return commits >= 4 && files[f].Commits*2 > commits
+	}
+	for p, n := range pairs {
+		if n < 2 || ubiquitous(p[0]) || ubiquitous(p[1]) {
+			continue
+		}
+		if jaccard := float64(n) / float64(files[p[0]].Commits+files[p[1]].Commits-n); jaccard < 0.4 {
+			continue
+		}
+		for _, f := range p {
+			if _, ok := parent[f]; !ok {
+				parent[f] = f
+			}
+		}
+		ra, rb := find(p[0]), find(p[1])
+		if ra != rb {
+			parent[ra] = rb
+			weight[rb] += weight[ra]
+			delete(weight, ra)
+		}
+		weight[find(p[1])] += n
+	}
+
+	groups := make(map[string][]string)
+	for f := range parent {
+		root := find(f)
+		groups[root] = append(groups[root], f)
+	}
+
+	var roots []string
+	for r := range groups {
+		roots = append(roots, r)
+		sort.Strings(groups[r])
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		if weight[roots[i]] != weight[roots[j]] {
+			return weight[roots[i]] > weight[roots[j]]
+		}
+		return roots[i] < roots[j]
+	})
+
+	var clusters [][]string
+	for _, r := range roots {
+		if len(clusters) == limit {
+			break
+		}
+		clusters = append(clusters, groups[r])
+	}
+	return clusters
+}
+
+// Render lays the analysis out one fact per line.
+func (h *Hotspots) Render() string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Window: since %s (%d commits)", h.Window, h.Commits))
+
+	var dirs []string
+	for _, d := range h.Dirs {
+		dirs = append(dirs, fmt.Sprintf("%s (%d commits)", d.Path, d.Commits))
+	}
+	if len(dirs) > 0 {
+		lines = append(lines, "Hot directories: "+strings.Join(dirs, ", "))
+	}
+	for _, f := range h.Files {
+		lines = append(lines, fmt.Sprintf("Hot file: %s — %d commits, +%d/-%d", f.Path, f.Commits, f.Added, f.Deleted))
+	}
+	for _, c := range h.Clusters {
+		shown := c
+		if len(shown) > 6 {
+			shown = shown[:6]
+		}
+		line := "Changed together: " + strings.Join(shown, ", ")
+		if extra := len(c) - len(shown); extra > 0 {
+			line += fmt.Sprintf(" (+%d more)", extra)
+		}
+		lines = append(lines, line)
+	}
+	var authors []string
+	for _, a := range h.Authors {
+		authors = append(authors, fmt.Sprintf("%s (%d commits, last %s)", a.Name, a.Commits, a.Last.Local().Format("2006-01-02")))
+	}
+	if len(authors) > 0 {
+		lines = append(lines, "Active authors: "+strings.Join(authors, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (h *Hotspots) metadata() string {
+	data, _ := json.Marshal(map[string]any{"type": "hotspots", "window": h.Window, "commits": h.Commits})
+	return string(data)
+}
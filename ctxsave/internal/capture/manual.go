@@ -3,6 +3,8 @@ package capture
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"ctxsave/internal/store"
 )
@@ -30,6 +32,10 @@ func CaptureFile(st *store.Store, project, filePath, tag string) (*store.Session
 		return nil, fmt.Errorf("read file: %w", err)
 	}
 
+	if strings.EqualFold(filepath.Ext(filePath), ".ipynb") {
+		return CaptureNotebook(st, project, data, filePath, tag)
+	}
+
 	content := truncate(string(data), 10000)
 	meta := fmt.Sprintf(`{"path":"%s","tag":"%s"}`, filePath, tag)
 	label := fmt.Sprintf("file: %s", filePath)
@@ -39,9 +45,15 @@ func CaptureFile(st *store.Store, project, filePath, tag string) (*store.Session
 		return nil, err
 	}
 
-	if _, err := st.AddEntry(sess.ID, store.EntryFile, content, meta, 0); err != nil {
+	entry, err := st.AddEntry(sess.ID, store.EntryFile, content, meta, 0)
+	if err != nil {
 		return nil, err
 	}
+	if tag != "" {
+		if err := st.TagEntry(entry.ID, tag); err != nil {
+			return nil, err
+		}
+	}
 
 	return sess, nil
 }
@@ -0,0 +1,173 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+// maxNotebookOutput is the largest text output kept from a cell; bigger
+// ones (dataframes, progress logs) are replaced by a one-line marker.
+const maxNotebookOutput = 1000
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+type notebook struct {
+	Metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType       string          `json:"cell_type"`
+		Source         json.RawMessage `json:"source"`
+		ExecutionCount *int            `json:"execution_count"`
+		Outputs        []struct {
+			OutputType string                     `json:"output_type"`
+			Text       json.RawMessage            `json:"text"`
+			Data       map[string]json.RawMessage `json:"data"`
+			Ename      string                     `json:"ename"`
+			Evalue     string                     `json:"evalue"`
+			Traceback  []string                   `json:"traceback"`
+		} `json:"outputs"`
+	} `json:"cells"`
+}
+
+// CaptureNotebook stores a Jupyter notebook as its markdown and code cells
+// with small text outputs, in chunks split at markdown headings. Images and
+// oversized outputs are dropped, and each error output is stored as an
+// error entry with its traceback.
+func CaptureNotebook(st *store.Store, project string, data []byte, filePath, tag string) (*store.Session, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("parse notebook: %w", err)
+	}
+
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = "python"
+	}
+
+	var sb strings.Builder
+	var errs []parsedEntry
+	for i, cell := range nb.Cells {
+		source := strings.TrimSpace(notebookText(cell.Source))
+		switch cell.CellType {
+		case "markdown":
+			if source != "" {
+				sb.WriteString(source + "\n\n")
+			}
+		case "code":
+			if source == "" {
+				continue
+			}
+			// a cell that never ran has no count, as Jupyter shows it
+			label := "In [ ]"
+			if cell.ExecutionCount != nil {
+				label = fmt.Sprintf("In [%d]", *cell.ExecutionCount)
+			}
+			sb.WriteString(fmt.Sprintf("%s:\n```%s\n%s\n```\n", label, lang, source))
+
+			for _, out := range cell.Outputs {
+				switch out.OutputType {
+				case "error":
+					trace := ansiEscape.ReplaceAllString(strings.Join(out.Traceback, "\n"), "")
+					meta, _ := json.Marshal(map[string]any{"source": "notebook", "path": filePath, "cell": i + 1})
+					errs = append(errs, parsedEntry{
+						Type:    store.EntryError,
+						Content: truncate(fmt.Sprintf("%s: %s (cell %d)\n%s", out.Ename, out.Evalue, i+1, trace), 2000),
+						Meta:    string(meta),
+					})
+					sb.WriteString(fmt.Sprintf("Error: %s: %s\n", out.Ename, out.Evalue))
+				case "stream":
+					sb.WriteString(notebookOutput(notebookText(out.Text)))
+				case "execute_result", "display_data":
+					// only text/plain survives; images, HTML and widgets are dropped,
+					// along with the placeholder text that accompanies a plot
+					if plain, ok := out.Data["text/plain"]; ok && !hasImage(out.Data) {
+						sb.WriteString(notebookOutput(notebookText(plain)))
+					}
+				}
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	content := strings.TrimSpace(sb.String())
+	if content == "" && len(errs) == 0 {
+		return nil, fmt.Errorf("notebook has no cells with content")
+	}
+
+	// long notebooks are split at markdown headings like docs
+	chunks := chunkByHeading(content, docChunkSize)
+	var entries []parsedEntry
+	for i, c := range chunks {
+		meta := map[string]any{"path": filePath, "tag": tag, "format": "ipynb"}
+		if len(chunks) > 1 {
+			meta["chunk"] = i + 1
+			meta["chunks"] = len(chunks)
+		}
+		if c.Heading != "" {
+			meta["heading"] = c.Heading
+		}
+		m, _ := json.Marshal(meta)
+		entries = append(entries, parsedEntry{Type: store.EntryFile, Content: c.Text, Meta: string(m)})
+	}
+	entries = append(entries, errs...)
+
+	sess, err := st.CreateSession("file", project, fmt.Sprintf("file: %s", filePath))
+	if err != nil {
+		return nil, err
+	}
+	// a saved notebook only shows each cell's last run, so errors aren't
+	// paired with resolutions the way transcript errors are
+	for i, pe := range entries {
+		entry, err := st.AddEntry(sess.ID, pe.Type, pe.Content, pe.Meta, i)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(chunks) && tag != "" {
+			if err := st.TagEntry(entry.ID, tag); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sess, nil
+}
+
+// notebookText decodes a multiline notebook field, stored either as one
+// string or as a list of lines.
+func notebookText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var lines []string
+	if json.Unmarshal(raw, &lines) == nil {
+		return strings.Join(lines, "")
+	}
+	return ""
+}
+
+func hasImage(data map[string]json.RawMessage) bool {
+	for mime := range data {
+		if strings.HasPrefix(mime, "image/") {
+			return true
+		}
+	}
+	return false
+}
+
+func notebookOutput(text string) string {
+	text = strings.TrimSpace(ansiEscape.ReplaceAllString(text, ""))
+	if text == "" {
+		return ""
+	}
+	if len(text) > maxNotebookOutput {
+		return fmt.Sprintf("[output omitted: %d lines]\n", strings.Count(text, "\n")+1)
+	}
+	return "Out:\n" + text + "\n"
+}
@@ -0,0 +1,51 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ctxsave/internal/rules"
+	"ctxsave/internal/store"
+)
+
+// RulePreview is one message of a transcript and what the classification
+// rules made of it.
+type RulePreview struct {
+	Role     string
+	Original store.EntryType
+	rules.Result
+}
+
+// PreviewRules parses a transcript and runs the rules over its messages
+// without storing anything. Cursor transcripts (.jsonl or text) and Aider
+// chat histories (.md) are supported.
+func PreviewRules(set *rules.Set, transcriptPath string) ([]RulePreview, error) {
+	data, err := os.ReadFile(transcriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("open transcript: %w", err)
+	}
+
+	var entries []parsedEntry
+	switch filepath.Ext(transcriptPath) {
+	case ".jsonl":
+		entries, err = parseJSONLEntries(data)
+		if err != nil {
+			return nil, err
+		}
+	case ".md":
+		entries = parseAiderChat(data)
+	default:
+		entries = parseTextEntries(data)
+	}
+
+	previews := make([]RulePreview, 0, len(entries))
+	for _, pe := range entries {
+		previews = append(previews, RulePreview{
+			Role:     pe.Role,
+			Original: pe.Type,
+			Result:   set.Apply(pe.Role, pe.Type, pe.Content),
+		})
+	}
+	return previews, nil
+}
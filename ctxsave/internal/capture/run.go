@@ -0,0 +1,191 @@
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"ctxsave/internal/store"
+)
+
+const runTailBytes = 64 * 1024
+
+type RunResult struct {
+	Command  string
+	ExitCode int
+	Duration time.Duration
+	Signal   string // set when a signal ended the command
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+	return len(p), nil
+}
+
+// RunAndCapture runs the command with its output teed to stdout/stderr and
+// records the invocation. A failing command is stored as an open error; a
+// later successful run of the same command resolves earlier failures.
+// Signals don't end ctxsave before the run is recorded. Ctrl-C and a
+// terminal hangup already reach the command through the terminal's process
+// group, so ctxsave ignores them rather than sending a second one, which
+// many tools take as "quit without cleaning up". A SIGTERM sent to ctxsave
+// alone is passed on.
+func RunAndCapture(st *store.Store, projectDir, project string, argv []string, stdin io.Reader, stdout, stderr io.Writer) (*store.Session, *RunResult, error) {
+	if len(argv) == 0 {
+		return nil, nil, fmt.Errorf("no command given — use 'ctxsave run -- <cmd> args...'")
+	}
+
+	tail := &tailBuffer{max: runTailBytes}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = projectDir
+	cmd.Stdin = stdin
+	cmd.Stdout = io.MultiWriter(stdout, tail)
+	cmd.Stderr = io.MultiWriter(stderr, tail)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("run %s: %w", argv[0], err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if sig == syscall.SIGTERM {
+					cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	runErr := cmd.Wait()
+	signal.Stop(sigs)
+	close(done)
+	result := &RunResult{Command: shellJoin(argv), Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case runErr == nil:
+	case errors.As(runErr, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			// report it the way a shell would
+			result.ExitCode = 128 + int(ws.Signal())
+			result.Signal = ws.Signal().String()
+		}
+	default:
+		return nil, nil, fmt.Errorf("run %s: %w", argv[0], runErr)
+	}
+
+	sess, err := st.CreateSession("run", project, truncate(result.Command, 80))
+	if err != nil {
+		return nil, result, err
+	}
+
+	output := trimRunOutput(string(tail.buf))
+	meta := map[string]any{
+		"source":      "run",
+		"command":     result.Command,
+		"exit_code":   result.ExitCode,
+		"duration_ms": result.Duration.Milliseconds(),
+	}
+
+	entryType := store.EntryCommand
+	content := result.Command
+	if result.ExitCode != 0 {
+		entryType = store.EntryError
+		meta["status"] = "open"
+		content = fmt.Sprintf("`%s` failed with exit code %d after %s", truncate(result.Command, 300), result.ExitCode, result.Duration.Round(time.Millisecond))
+		if result.Signal != "" {
+			meta["signal"] = result.Signal
+			content = fmt.Sprintf("`%s` was stopped by a signal (%s) after %s", truncate(result.Command, 300), result.Signal, result.Duration.Round(time.Millisecond))
+		}
+	}
+	if output != "" {
+		content += "\n" + output
+	}
+
+	data, _ := json.Marshal(meta)
+	if _, err := st.AddEntry(sess.ID, entryType, content, string(data), 0); err != nil {
+		return nil, result, err
+	}
+
+	if result.ExitCode == 0 {
+		if err := resolvePreviousRuns(st, result.Command); err != nil {
+			return sess, result, err
+		}
+	}
+
+	return sess, result, nil
+}
+
+func resolvePreviousRuns(st *store.Store, command string) error {
+	errs, err := st.GetEntriesByType(store.EntryError)
+	if err != nil {
+		return err
+	}
+	for _, e := range errs {
+		var meta map[string]any
+		if json.Unmarshal([]byte(e.Metadata), &meta) != nil {
+			continue
+		}
+		if meta["source"] != "run" || meta["command"] != command || meta["status"] != "open" {
+			continue
+		}
+		meta["status"] = "resolved"
+		meta["resolution"] = "rerun"
+		meta["resolved_by"] = fmt.Sprintf("a later run of `%s` succeeded", truncate(command, 100))
+		data, _ := json.Marshal(meta)
+		if err := st.UpdateEntryMetadata(e.ID, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trimRunOutput keeps the last 30 lines, which is where failures are reported.
+func trimRunOutput(out string) string {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) > 30 {
+		lines = lines[len(lines)-30:]
+	}
+	s := strings.TrimSpace(strings.Join(lines, "\n"))
+	if len(s) > 1500 {
+		s = "..." + s[len(s)-1500:]
+	}
+	return s
+}
+
+// shellJoin renders argv the way it would be typed, quoting arguments that
+// contain spaces or quotes.
+func shellJoin(argv []string) string {
+	parts := make([]string, len(argv))
+	for i, a := range argv {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"$`\\") {
+			parts[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		} else {
+			parts[i] = a
+		}
+	}
+	return strings.Join(parts, " ")
+}
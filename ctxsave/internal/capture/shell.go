@@ -0,0 +1,415 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctxsave/internal/store"
+)
+
+type shellCommand struct {
+	Time time.Time // zero when the history has no timestamps
+	Dir  string    // "" when unknown
+	Cmd  string
+	Exit *int // nil when unknown
+}
+
+type ShellCaptureOptions struct {
+	Shell   string    // bash, zsh or fish; "" = detect from $SHELL
+	Since   time.Time // zero = no lower bound
+	AllDirs bool      // keep commands whose directory can't be tied to the project
+	Limit   int       // max commands kept from histories without timestamps
+}
+
+// trivialCommands are dropped because they say nothing about the work.
+var trivialCommands = map[string]bool{
+	"ls": true, "ll": true, "la": true, "cd": true, "pwd": true, "clear": true,
+	"exit": true, "history": true, "cat": true, "less": true, "more": true,
+	"vim": true, "vi": true, "nvim": true, "nano": true, "code": true, "cursor": true,
+	"man": true, "which": true, "echo": true, "z": true, "j": true, "tree": true,
+}
+
+// ShellHookLog is where the installed shell hook appends
+// "<unix time>\t<exit code>\t<cwd>\t<command>" lines.
+func ShellHookLog() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(homeDir, ".ctxsave", "shell.log"), nil
+}
+
+func DetectShell() string {
+	name := filepath.Base(os.Getenv("SHELL"))
+	switch name {
+	case "zsh", "fish":
+		return name
+	default:
+		return "bash"
+	}
+}
+
+// CaptureFromShell records the commands run inside projectDir. The hook log
+// is used when it has commands for the project since it knows each
+// command's directory and exit code; otherwise the shell's history file is
+// read and directories are inferred from cd commands.
+func CaptureFromShell(st *store.Store, projectDir, project string, opts ShellCaptureOptions) (*store.Session, error) {
+	shell := opts.Shell
+	if shell == "" {
+		shell = DetectShell()
+	}
+
+	var cmds []shellCommand
+	source, logPath, logSize := "", "", int64(0)
+	if p, err := ShellHookLog(); err == nil {
+		if _, err := os.Stat(p); err == nil {
+			logged, size, err := readHookLog(p)
+			if err != nil {
+				return nil, err
+			}
+			// the hook may run in another shell, or this project's lines
+			// may all have been consumed already
+			if cmds = filterShellCommands(logged, projectDir, opts); len(cmds) > 0 {
+				source, logPath, logSize = "hook", p, size
+			}
+		}
+	}
+	if source == "" {
+		histPath, err := historyFile(shell)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(histPath)
+		if err != nil {
+			return nil, fmt.Errorf("read %s history: %w", shell, err)
+		}
+		switch shell {
+		case "zsh":
+			cmds = parseZshHistory(data)
+		case "fish":
+			cmds = parseFishHistory(data)
+		default:
+			cmds = parseBashHistory(data)
+		}
+		inferDirs(cmds)
+		cmds = filterShellCommands(cmds, projectDir, opts)
+		source = shell
+	}
+
+	if len(cmds) == 0 {
+		hint := ""
+		if source != "hook" {
+			hint = " — install the hook with 'ctxsave capture shell install-hook' to track directories"
+		}
+		return nil, fmt.Errorf("no shell commands found for this project in the time window%s", hint)
+	}
+
+	label := "shell"
+	if !opts.Since.IsZero() {
+		label = fmt.Sprintf("shell (since %s)", opts.Since.Local().Format("2006-01-02 15:04"))
+	}
+	sess, err := st.CreateSession("shell", project, label)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range cmds {
+		meta := map[string]any{"source": source}
+		if !c.Time.IsZero() {
+			meta["time"] = c.Time.UTC().Format(time.RFC3339)
+		}
+		if c.Dir != "" {
+			meta["dir"] = c.Dir
+		}
+		entryType := store.EntryCommand
+		content := c.Cmd
+		if c.Exit != nil {
+			meta["exit_code"] = *c.Exit
+			if *c.Exit != 0 {
+				entryType = store.EntryError
+				content = fmt.Sprintf("`%s` exited with status %d", truncate(c.Cmd, 300), *c.Exit)
+			}
+		}
+		data, _ := json.Marshal(meta)
+		if _, err := st.AddEntry(sess.ID, entryType, truncate(content, 500), string(data), i); err != nil {
+			return nil, err
+		}
+	}
+
+	if logPath != "" {
+		if err := compactHookLog(logPath, logSize, projectDir, opts); err != nil {
+			return nil, err
+		}
+	}
+	return sess, nil
+}
+
+func historyFile(shell string) (string, error) {
+	if hf := os.Getenv("HISTFILE"); hf != "" && shell != "fish" {
+		return hf, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	switch shell {
+	case "zsh":
+		for _, name := range []string{".zsh_history", ".zhistory"} {
+			p := filepath.Join(homeDir, name)
+			if _, err := os.Stat(p); err == nil {
+				return p, nil
+			}
+		}
+		return filepath.Join(homeDir, ".zsh_history"), nil
+	case "fish":
+		return filepath.Join(homeDir, ".local", "share", "fish", "fish_history"), nil
+	default:
+		return filepath.Join(homeDir, ".bash_history"), nil
+	}
+}
+
+// readHookLog parses the hook log and returns its size, the offset a later
+// compactHookLog treats as consumed.
+func readHookLog(path string) ([]shellCommand, int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read shell log: %w", err)
+	}
+
+	var cmds []shellCommand
+	for _, line := range strings.Split(string(data), "\n") {
+		if c, ok := parseHookLine(line); ok {
+			cmds = append(cmds, c)
+		}
+	}
+	return cmds, int64(len(data)), nil
+}
+
+func parseHookLine(line string) (shellCommand, bool) {
+	parts := strings.SplitN(line, "\t", 4)
+	if len(parts) < 4 {
+		return shellCommand{}, false
+	}
+	ts, err1 := strconv.ParseInt(parts[0], 10, 64)
+	code, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return shellCommand{}, false
+	}
+	return shellCommand{
+		Time: time.Unix(ts, 0),
+		Exit: &code,
+		Dir:  parts[2],
+		Cmd:  strings.TrimSpace(parts[3]),
+	}, true
+}
+
+// shellLogRetention is how long the hook log keeps commands no capture has
+// consumed, such as those run in directories without a ctxsave project.
+const shellLogRetention = 30 * 24 * time.Hour
+
+// compactHookLog drops the lines in the first size bytes of the hook log
+// that this capture consumed, along with lines older than
+// shellLogRetention. The log is shared by every project, so commands run
+// elsewhere are kept for their own captures. Lines the hook appended since
+// the capture read the log are carried over unread; the log is read again
+// just before it is replaced so that appends made while compacting survive.
+func compactHookLog(path string, size int64, projectDir string, opts ShellCaptureOptions) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read shell log: %w", err)
+	}
+	if int64(len(data)) < size {
+		// truncated by someone else in the meantime
+		return nil
+	}
+
+	project := filepath.Clean(projectDir)
+	cutoff := time.Now().Add(-shellLogRetention)
+	var kept strings.Builder
+	for _, line := range strings.Split(string(data[:size]), "\n") {
+		c, ok := parseHookLine(line)
+		if !ok || c.Time.Before(cutoff) {
+			continue
+		}
+		consumed := (opts.AllDirs || insideProject(c, project)) &&
+			(opts.Since.IsZero() || !c.Time.Before(opts.Since))
+		if consumed {
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteString("\n")
+	}
+	kept.Write(data[size:])
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(kept.String()), 0644); err != nil {
+		return fmt.Errorf("write shell log: %w", err)
+	}
+	if latest, err := os.ReadFile(path); err == nil && len(latest) > len(data) {
+		if err := appendFile(tmp, latest[len(data):]); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("write shell log: %w", err)
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replace shell log: %w", err)
+	}
+	return nil
+}
+
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseBashHistory reads ~/.bash_history. With HISTTIMEFORMAT set, bash
+// writes a "#<unix time>" line before each command.
+func parseBashHistory(data []byte) []shellCommand {
+	var cmds []shellCommand
+	var ts time.Time
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "#") {
+			if n, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
+				ts = time.Unix(n, 0)
+				continue
+			}
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		cmds = append(cmds, shellCommand{Time: ts, Cmd: line})
+		ts = time.Time{}
+	}
+	return cmds
+}
+
+// parseZshHistory reads plain and extended zsh history. Extended entries
+// look like ": <start>:<elapsed>;<command>", and multi-line commands
+// continue with a trailing backslash.
+func parseZshHistory(data []byte) []shellCommand {
+	var cmds []shellCommand
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + "\n" + lines[i]
+		}
+
+		var ts time.Time
+		if strings.HasPrefix(line, ": ") {
+			if semi := strings.Index(line, ";"); semi > 0 {
+				meta := strings.SplitN(line[2:semi], ":", 2)
+				if n, err := strconv.ParseInt(strings.TrimSpace(meta[0]), 10, 64); err == nil {
+					ts = time.Unix(n, 0)
+				}
+				line = line[semi+1:]
+			}
+		}
+		line = strings.TrimSpace(line)
+		if line != "" {
+			cmds = append(cmds, shellCommand{Time: ts, Cmd: line})
+		}
+	}
+	return cmds
+}
+
+// parseFishHistory reads fish's YAML-like history: "- cmd:" items followed
+// by "when:" and optional "paths:" fields.
+func parseFishHistory(data []byte) []shellCommand {
+	var cmds []shellCommand
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "- cmd: "):
+			cmd := strings.TrimPrefix(line, "- cmd: ")
+			cmd = strings.ReplaceAll(cmd, `\n`, "\n")
+			cmd = strings.ReplaceAll(cmd, `\\`, `\`)
+			cmds = append(cmds, shellCommand{Cmd: strings.TrimSpace(cmd)})
+		case strings.HasPrefix(strings.TrimSpace(line), "when: ") && len(cmds) > 0:
+			if n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "when: ")), 10, 64); err == nil {
+				cmds[len(cmds)-1].Time = time.Unix(n, 0)
+			}
+		}
+	}
+	return cmds
+}
+
+// inferDirs follows cd commands through a history that doesn't record
+// directories. Relative cds only resolve once an absolute one was seen.
+func inferDirs(cmds []shellCommand) {
+	homeDir, _ := os.UserHomeDir()
+	cwd := ""
+	for i := range cmds {
+		cmds[i].Dir = cwd
+
+		fields := strings.Fields(cmds[i].Cmd)
+		if len(fields) == 0 || fields[0] != "cd" || strings.ContainsAny(cmds[i].Cmd, ";&|") {
+			continue
+		}
+		if len(fields) == 1 || fields[1] == "~" {
+			cwd = homeDir
+			continue
+		}
+		target := fields[1]
+		switch {
+		case target == "-":
+			cwd = ""
+		case strings.HasPrefix(target, "~/"):
+			cwd = filepath.Join(homeDir, target[2:])
+		case filepath.IsAbs(target):
+			cwd = filepath.Clean(target)
+		case cwd != "":
+			cwd = filepath.Join(cwd, target)
+		}
+	}
+}
+
+func filterShellCommands(cmds []shellCommand, projectDir string, opts ShellCaptureOptions) []shellCommand {
+	project := filepath.Clean(projectDir)
+	timed := false
+
+	var kept []shellCommand
+	for _, c := range cmds {
+		if c.Cmd == "" {
+			continue
+		}
+		if fields := strings.Fields(c.Cmd); trivialCommands[fields[0]] {
+			continue
+		}
+		if !c.Time.IsZero() {
+			timed = true
+			if !opts.Since.IsZero() && c.Time.Before(opts.Since) {
+				continue
+			}
+		}
+		if !insideProject(c, project) && !opts.AllDirs {
+			continue
+		}
+		kept = append(kept, c)
+	}
+
+	if !timed && opts.Limit > 0 && len(kept) > opts.Limit {
+		kept = kept[len(kept)-opts.Limit:]
+	}
+	return kept
+}
+
+// insideProject reports whether c ran in the project or names it.
+func insideProject(c shellCommand, project string) bool {
+	return c.Dir == project || strings.HasPrefix(c.Dir, project+string(filepath.Separator)) ||
+		strings.Contains(c.Cmd, project)
+}
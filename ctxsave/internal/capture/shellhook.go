@@ -0,0 +1,105 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const hookMarker = "# ctxsave shell hook"
+
+var shellHooks = map[string]string{
+	"bash": `# ctxsave shell hook — logs each command with its exit code and directory
+__ctxsave_log() {
+	local exit_status=$?
+	local cmd
+	cmd=$(HISTTIMEFORMAT= builtin history 1 | sed 's/^ *[0-9]* *//')
+	if [ -n "$cmd" ] && [ "$cmd" != "$__ctxsave_last" ]; then
+		printf '%s\t%s\t%s\t%s\n' "$(date +%s)" "$exit_status" "$PWD" "${cmd//$'\n'/ }" >> "$HOME/.ctxsave/shell.log"
+	fi
+	__ctxsave_last=$cmd
+	return $exit_status
+}
+case ";$PROMPT_COMMAND;" in
+	*";__ctxsave_log;"*) ;;
+	*) PROMPT_COMMAND="__ctxsave_log${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`,
+	"zsh": `# ctxsave shell hook — logs each command with its exit code and directory
+__ctxsave_preexec() { __ctxsave_cmd=$1; }
+__ctxsave_precmd() {
+	local exit_status=$?
+	if [[ -n $__ctxsave_cmd ]]; then
+		printf '%s\t%s\t%s\t%s\n' "$(date +%s)" "$exit_status" "$PWD" "${__ctxsave_cmd//$'\n'/ }" >> "$HOME/.ctxsave/shell.log"
+	fi
+	__ctxsave_cmd=
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec __ctxsave_preexec
+add-zsh-hook precmd __ctxsave_precmd
+`,
+	"fish": `# ctxsave shell hook — logs each command with its exit code and directory
+function __ctxsave_log --on-event fish_postexec
+	set -l exit_status $status
+	printf '%s\t%s\t%s\t%s\n' (date +%s) $exit_status $PWD (string replace -a \n ' ' -- $argv[1]) >> $HOME/.ctxsave/shell.log
+end
+`,
+}
+
+// InstallShellHook writes the hook script to ~/.ctxsave and sources it from
+// the shell's rc file. Running it again only refreshes the script.
+func InstallShellHook(shell string) (string, string, error) {
+	script, ok := shellHooks[shell]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported shell %q — use bash, zsh, or fish", shell)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("get home dir: %w", err)
+	}
+
+	hookDir := filepath.Join(homeDir, ".ctxsave")
+	if err := os.MkdirAll(hookDir, 0755); err != nil {
+		return "", "", fmt.Errorf("create %s: %w", hookDir, err)
+	}
+	hookPath := filepath.Join(hookDir, "hook."+shell)
+	if err := os.WriteFile(hookPath, []byte(script), 0644); err != nil {
+		return "", "", fmt.Errorf("write hook: %w", err)
+	}
+
+	var rcPath, sourceLine string
+	switch shell {
+	case "zsh":
+		rcPath = filepath.Join(homeDir, ".zshrc")
+		sourceLine = fmt.Sprintf("[ -f %q ] && source %q", hookPath, hookPath)
+	case "fish":
+		rcPath = filepath.Join(homeDir, ".config", "fish", "config.fish")
+		sourceLine = fmt.Sprintf("test -f %q; and source %q", hookPath, hookPath)
+	default:
+		rcPath = filepath.Join(homeDir, ".bashrc")
+		sourceLine = fmt.Sprintf("[ -f %q ] && source %q", hookPath, hookPath)
+	}
+
+	rc, err := os.ReadFile(rcPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("read %s: %w", rcPath, err)
+	}
+	if strings.Contains(string(rc), hookMarker) {
+		return hookPath, rcPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rcPath), 0755); err != nil {
+		return "", "", err
+	}
+	f, err := os.OpenFile(rcPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", "", fmt.Errorf("open %s: %w", rcPath, err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "\n%s\n%s\n", hookMarker, sourceLine); err != nil {
+		return "", "", fmt.Errorf("update %s: %w", rcPath, err)
+	}
+	return hookPath, rcPath, nil
+}
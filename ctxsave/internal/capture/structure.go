@@ -0,0 +1,251 @@
+package capture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+const maxCtagsPerFile = 25
+
+// skipDirs are never descended into when mapping the project.
+var skipDirs = map[string]bool{
+	"vendor": true, "node_modules": true, "testdata": true, "dist": true, "build": true, "target": true,
+}
+
+type structureMeta struct {
+	Version int    `json:"version"`
+	Hash    string `json:"hash"`
+}
+
+// CaptureStructure stores a compact map of the project's packages, exported
+// types and function signatures. Each capture is a new version; nothing is
+// stored when the map hasn't changed since the last one.
+func CaptureStructure(st *store.Store, projectDir, project string) (*store.Session, int, error) {
+	goMap, err := goStructure(projectDir)
+	if err != nil {
+		return nil, 0, err
+	}
+	tagMap := ctagsStructure(projectDir)
+
+	var parts []string
+	if goMap != "" {
+		parts = append(parts, goMap)
+	}
+	if tagMap != "" {
+		parts = append(parts, tagMap)
+	}
+	if len(parts) == 0 {
+		return nil, 0, fmt.Errorf("no Go packages found and universal-ctags is not available for other languages")
+	}
+	content := strings.Join(parts, "\n")
+
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	version := 1
+	previous, err := st.GetEntriesByType(store.EntryStructure)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n := len(previous); n > 0 {
+		var last structureMeta
+		_ = json.Unmarshal([]byte(previous[n-1].Metadata), &last)
+		if last.Hash == hash {
+			return nil, last.Version, nil
+		}
+		version = last.Version + 1
+	}
+
+	sess, err := st.CreateSession("structure", project, fmt.Sprintf("structure v%d", version))
+	if err != nil {
+		return nil, 0, err
+	}
+	meta, _ := json.Marshal(structureMeta{Version: version, Hash: hash})
+	if _, err := st.AddEntry(sess.ID, store.EntryStructure, content, string(meta), 0); err != nil {
+		return nil, 0, err
+	}
+	return sess, version, nil
+}
+
+// goStructure parses every non-test Go file and lists, per package
+// directory, the exported types and funcs with their signatures. Package
+// lines are unindented and members are indented by two spaces.
+func goStructure(projectDir string) (string, error) {
+	fset := token.NewFileSet()
+	members := make(map[string][]string)
+
+	err := filepath.Walk(projectDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != projectDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(projectDir, filepath.Dir(path))
+		key := fmt.Sprintf("%s (package %s)", filepath.ToSlash(rel), file.Name.Name)
+		members[key] = append(members[key], exportedDecls(fset, file)...)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("scan Go files: %w", err)
+	}
+	if len(members) == 0 {
+		return "", nil
+	}
+
+	var keys []string
+	for k := range members {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k + "\n")
+		for _, m := range members[k] {
+			sb.WriteString("  " + m + "\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+func exportedDecls(fset *token.FileSet, file *ast.File) []string {
+	var out []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if !ts.Name.IsExported() {
+					continue
+				}
+				kind := "type"
+				switch ts.Type.(type) {
+				case *ast.StructType:
+					kind = "struct"
+				case *ast.InterfaceType:
+					kind = "interface"
+				default:
+					kind = nodeString(fset, ts.Type)
+				}
+				out = append(out, fmt.Sprintf("type %s %s", ts.Name.Name, kind))
+			}
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			recv := ""
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recvType := nodeString(fset, d.Recv.List[0].Type)
+				if !ast.IsExported(strings.TrimLeft(recvType, "*")) {
+					continue
+				}
+				recv = "(" + recvType + ") "
+			}
+			sig := strings.TrimPrefix(nodeString(fset, d.Type), "func")
+			out = append(out, fmt.Sprintf("func %s%s%s", recv, d.Name.Name, sig))
+		}
+	}
+	return out
+}
+
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	s := strings.Join(strings.Fields(buf.String()), " ")
+	return truncate(s, 200)
+}
+
+// ctagsStructure maps non-Go sources with universal-ctags, if installed.
+func ctagsStructure(projectDir string) string {
+	if _, err := exec.LookPath("ctags"); err != nil {
+		return ""
+	}
+
+	cmd := exec.Command("ctags", "-R", "--output-format=json", "--fields=+KS", "--exclude=*.go",
+		"--exclude=.git", "--exclude=.ctxsave", "--exclude=node_modules", "--exclude=vendor", "-f", "-", ".")
+	cmd.Dir = projectDir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	type tag struct {
+		Type      string `json:"_type"`
+		Name      string `json:"name"`
+		Path      string `json:"path"`
+		Kind      string `json:"kind"`
+		Signature string `json:"signature"`
+		Scope     string `json:"scope"`
+	}
+
+	byFile := make(map[string][]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		var t tag
+		if json.Unmarshal([]byte(line), &t) != nil || t.Type != "tag" {
+			continue
+		}
+		switch t.Kind {
+		case "function", "method", "class", "interface", "struct", "module", "type", "enum", "trait":
+		default:
+			continue
+		}
+		if strings.HasPrefix(t.Name, "_") || len(byFile[t.Path]) >= maxCtagsPerFile {
+			continue
+		}
+		name := t.Name
+		if t.Scope != "" {
+			name = t.Scope + "." + name
+		}
+		byFile[t.Path] = append(byFile[t.Path], fmt.Sprintf("%s %s%s", t.Kind, name, t.Signature))
+	}
+	if len(byFile) == 0 {
+		return ""
+	}
+
+	var files []string
+	for f := range byFile {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	var sb strings.Builder
+	for _, f := range files {
+		sb.WriteString(filepath.ToSlash(f) + "\n")
+		for _, t := range byFile[f] {
+			sb.WriteString("  " + t + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,309 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+// TestReport is the outcome of one test run, from go test -json or JUnit XML.
+type TestReport struct {
+	Format   string
+	Passed   int
+	Failed   int
+	Skipped  int
+	Packages int
+	Covered  []string // the packages or suites the run included
+	Failures []TestFailure
+}
+
+type TestFailure struct {
+	Package string
+	Name    string // "" for a package that failed without a failing test, e.g. a build error
+	Output  string
+}
+
+type goTestEvent struct {
+	Action      string `json:"Action"`
+	Package     string `json:"Package"`
+	ImportPath  string `json:"ImportPath"`
+	Test        string `json:"Test"`
+	Output      string `json:"Output"`
+	FailedBuild string `json:"FailedBuild"`
+}
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure"`
+	Error     *junitProblem `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+	SystemOut string        `xml:"system-out"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseTestResults detects the format from the first non-blank byte: JUnit
+// reports are XML, go test -json is one JSON event per line.
+func ParseTestResults(data []byte) (*TestReport, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("no test output to parse")
+	}
+	if trimmed[0] == '<' {
+		return parseJUnit(trimmed)
+	}
+	return parseGoTestJSON(trimmed)
+}
+
+func parseGoTestJSON(data []byte) (*TestReport, error) {
+	type key struct{ pkg, test string }
+	output := make(map[key][]string)
+	results := make(map[key]string)
+	buildOutput := make(map[string][]string)
+	failedBuild := make(map[string]string)
+	var order []key
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	events := 0
+	for scanner.Scan() {
+		var ev goTestEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue
+		}
+		events++
+		k := key{ev.Package, ev.Test}
+		switch ev.Action {
+		case "build-output":
+			// keyed by the full ImportPath, e.g. "example.com/p [example.com/p.test]",
+			// which is what the package's FailedBuild field refers to
+			if ev.ImportPath != "" {
+				buildOutput[ev.ImportPath] = append(buildOutput[ev.ImportPath], ev.Output)
+			}
+		case "output":
+			output[k] = append(output[k], ev.Output)
+		case "pass", "fail", "skip":
+			if _, ok := results[k]; !ok {
+				order = append(order, k)
+			}
+			results[k] = ev.Action
+			if ev.FailedBuild != "" {
+				failedBuild[ev.Package] = ev.FailedBuild
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if events == 0 {
+		return nil, fmt.Errorf("input is neither go test -json output nor JUnit XML")
+	}
+
+	// Subtests report on their own; a parent only counts when it has none,
+	// or when it failed while all of its subtests passed.
+	subtestFailed := make(map[key]bool)
+	hasSubtests := make(map[key]bool)
+	for _, k := range order {
+		for i := strings.LastIndex(k.test, "/"); i > 0; i = strings.LastIndex(k.test[:i], "/") {
+			parent := key{k.pkg, k.test[:i]}
+			hasSubtests[parent] = true
+			if results[k] == "fail" {
+				subtestFailed[parent] = true
+			}
+		}
+	}
+
+	report := &TestReport{Format: "go test"}
+	failedTests := make(map[string]bool)
+	for _, k := range order {
+		if k.test == "" {
+			report.Packages++
+			report.Covered = append(report.Covered, k.pkg)
+			continue
+		}
+		if hasSubtests[k] && (results[k] != "fail" || subtestFailed[k]) {
+			continue
+		}
+		switch results[k] {
+		case "pass":
+			report.Passed++
+		case "skip":
+			report.Skipped++
+		case "fail":
+			report.Failed++
+			failedTests[k.pkg] = true
+			report.Failures = append(report.Failures, TestFailure{
+				Package: k.pkg,
+				Name:    k.test,
+				Output:  trimTestOutput(output[k]),
+			})
+		}
+	}
+
+	// A package that fails with no failing test usually didn't build.
+	for _, k := range order {
+		if k.test == "" && results[k] == "fail" && !failedTests[k.pkg] {
+			out := output[k]
+			if fb, ok := failedBuild[k.pkg]; ok && len(buildOutput[fb]) > 0 {
+				out = buildOutput[fb]
+			}
+			report.Failures = append(report.Failures, TestFailure{
+				Package: k.pkg,
+				Output:  trimTestOutput(out),
+			})
+		}
+	}
+
+	return report, nil
+}
+
+func parseJUnit(data []byte) (*TestReport, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse JUnit XML: %w", err)
+	}
+
+	report := &TestReport{Format: "junit"}
+	covered := make(map[string]bool)
+	var walk func(s junitSuite)
+	walk = func(s junitSuite) {
+		if len(s.Cases) > 0 {
+			report.Packages++
+		}
+		for _, c := range s.Cases {
+			problem := c.Failure
+			if problem == nil {
+				problem = c.Error
+			}
+			pkg := c.ClassName
+			if pkg == "" {
+				pkg = s.Name
+			}
+			if !covered[pkg] {
+				covered[pkg] = true
+				report.Covered = append(report.Covered, pkg)
+			}
+			switch {
+			case problem != nil:
+				report.Failed++
+				out := strings.TrimSpace(problem.Message + "\n" + problem.Text)
+				report.Failures = append(report.Failures, TestFailure{
+					Package: pkg,
+					Name:    c.Name,
+					Output:  trimTestOutput(strings.SplitAfter(out, "\n")),
+				})
+			case c.Skipped != nil:
+				report.Skipped++
+			default:
+				report.Passed++
+			}
+		}
+		for _, child := range s.Suites {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return report, nil
+}
+
+// trimTestOutput drops go test's own framing lines and keeps the tail,
+// which is where the assertion and stack trace usually are.
+func trimTestOutput(lines []string) string {
+	var kept []string
+	for _, l := range lines {
+		t := strings.TrimRight(l, "\n")
+		trimmed := strings.TrimSpace(t)
+		if trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "# ") || strings.HasPrefix(trimmed, "--- ") ||
+			trimmed == "FAIL" || trimmed == "PASS" || strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "ok  \t") {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if len(kept) > 20 {
+		kept = kept[len(kept)-20:]
+	}
+	return truncate(strings.Join(kept, "\n"), 800)
+}
+
+func (r *TestReport) Summary() string {
+	status := "PASS"
+	if r.Failed > 0 || len(r.Failures) > 0 {
+		status = "FAIL"
+	}
+	s := fmt.Sprintf("%s: %s — %d passed, %d failed, %d skipped across %d packages",
+		r.Format, status, r.Passed, r.Failed, r.Skipped, r.Packages)
+
+	var names []string
+	for _, f := range r.Failures {
+		names = append(names, f.label())
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		s += "\nFailing: " + strings.Join(names, ", ")
+	}
+	return s
+}
+
+func (f TestFailure) label() string {
+	if f.Name == "" {
+		return f.Package + " (package failed)"
+	}
+	return f.Package + "." + f.Name
+}
+
+// CaptureTestResults stores each failure as an open EntryError and the run
+// as a single EntryTestResult summary.
+func CaptureTestResults(st *store.Store, project string, data []byte, label string) (*store.Session, *TestReport, error) {
+	report, err := ParseTestResults(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if label == "" {
+		label = report.Format
+	}
+	sess, err := st.CreateSession("test", project, label)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]any{
+		"format": report.Format, "passed": report.Passed, "failed": report.Failed, "skipped": report.Skipped,
+		"packages": report.Covered,
+	})
+	if _, err := st.AddEntry(sess.ID, store.EntryTestResult, report.Summary(), string(meta), 0); err != nil {
+		return nil, nil, err
+	}
+
+	for i, f := range report.Failures {
+		content := fmt.Sprintf("Test failed: %s", f.label())
+		if f.Output != "" {
+			content += "\n" + f.Output
+		}
+		meta, _ := json.Marshal(map[string]string{
+			"source": report.Format, "status": "open", "package": f.Package, "test": f.Name,
+		})
+		if _, err := st.AddEntry(sess.ID, store.EntryError, content, string(meta), i+1); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return sess, report, nil
+}
@@ -0,0 +1,80 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+)
+
+// buildFailureStream is go test -json output for a package that doesn't
+// compile, as produced by Go 1.24.
+const buildFailureStream = `{"ImportPath":"example.com/bt [example.com/bt.test]","Action":"build-output","Output":"# example.com/bt [example.com/bt.test]\n"}
+{"ImportPath":"example.com/bt [example.com/bt.test]","Action":"build-output","Output":"./bt.go:3:23: undefined: undefinedThing\n"}
+{"ImportPath":"example.com/bt [example.com/bt.test]","Action":"build-fail"}
+{"Time":"2026-10-18T18:51:52.438354136Z","Action":"start","Package":"example.com/bt"}
+{"Time":"2026-10-18T18:51:52.438588896Z","Action":"output","Package":"example.com/bt","Output":"FAIL\texample.com/bt [build failed]\n","OutputType":"frame"}
+{"Time":"2026-10-18T18:51:52.438616451Z","Action":"fail","Package":"example.com/bt","Elapsed":0,"FailedBuild":"example.com/bt [example.com/bt.test]"}
+`
+
+func TestParseGoTestJSONBuildFailure(t *testing.T) {
+	report, err := ParseTestResults([]byte(buildFailureStream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failures) != 1 {
+		t.Fatalf("got %d failures, want 1", len(report.Failures))
+	}
+	f := report.Failures[0]
+	if f.Package != "example.com/bt" || f.Name != "" {
+		t.Errorf("failure = %s %q, want package example.com/bt with no test name", f.Package, f.Name)
+	}
+	if !strings.Contains(f.Output, "undefined: undefinedThing") {
+		t.Errorf("failure output lost the compiler error:\n%s", f.Output)
+	}
+}
+
+func TestParseGoTestJSONBuildOutputWithoutImportPath(t *testing.T) {
+	if _, err := ParseTestResults([]byte(`{"Action":"build-output","Output":"x\n"}`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// subtestFailureStream is go test -json output for a test whose second
+// subtest fails, as produced by Go 1.24. The parent TestX fails only
+// because TestX/sub did.
+const subtestFailureStream = `{"Time":"2026-10-18T19:14:28.339157814Z","Action":"start","Package":"example.com/st"}
+{"Time":"2026-10-18T19:14:28.342569982Z","Action":"run","Package":"example.com/st","Test":"TestX"}
+{"Time":"2026-10-18T19:14:28.342627407Z","Action":"output","Package":"example.com/st","Test":"TestX","Output":"=== RUN   TestX\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342650643Z","Action":"run","Package":"example.com/st","Test":"TestX/ok"}
+{"Time":"2026-10-18T19:14:28.342654155Z","Action":"output","Package":"example.com/st","Test":"TestX/ok","Output":"=== RUN   TestX/ok\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342662242Z","Action":"output","Package":"example.com/st","Test":"TestX/ok","Output":"--- PASS: TestX/ok (0.00s)\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342666343Z","Action":"pass","Package":"example.com/st","Test":"TestX/ok","Elapsed":0}
+{"Time":"2026-10-18T19:14:28.342674182Z","Action":"run","Package":"example.com/st","Test":"TestX/sub"}
+{"Time":"2026-10-18T19:14:28.342676807Z","Action":"output","Package":"example.com/st","Test":"TestX/sub","Output":"=== RUN   TestX/sub\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342679842Z","Action":"output","Package":"example.com/st","Test":"TestX/sub","Output":"    st_test.go:7: boom\n","OutputType":"error"}
+{"Time":"2026-10-18T19:14:28.342683164Z","Action":"output","Package":"example.com/st","Test":"TestX/sub","Output":"--- FAIL: TestX/sub (0.00s)\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342685662Z","Action":"fail","Package":"example.com/st","Test":"TestX/sub","Elapsed":0}
+{"Time":"2026-10-18T19:14:28.342688459Z","Action":"output","Package":"example.com/st","Test":"TestX","Output":"--- FAIL: TestX (0.00s)\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342691217Z","Action":"fail","Package":"example.com/st","Test":"TestX","Elapsed":0}
+{"Time":"2026-10-18T19:14:28.34269674Z","Action":"output","Package":"example.com/st","Output":"FAIL\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342727113Z","Action":"output","Package":"example.com/st","Output":"FAIL\texample.com/st\t0.002s\n","OutputType":"frame"}
+{"Time":"2026-10-18T19:14:28.342734433Z","Action":"fail","Package":"example.com/st","Elapsed":0.004}
+`
+
+func TestParseGoTestJSONSubtestFailure(t *testing.T) {
+	report, err := ParseTestResults([]byte(subtestFailureStream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed != 1 || report.Failed != 1 {
+		t.Errorf("got %d passed, %d failed, want 1 and 1", report.Passed, report.Failed)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Name != "TestX/sub" {
+		t.Fatalf("failures = %+v, want only TestX/sub", report.Failures)
+	}
+	if !strings.Contains(report.Failures[0].Output, "boom") {
+		t.Errorf("failure output lost the assertion:\n%s", report.Failures[0].Output)
+	}
+	if len(report.Covered) != 1 || report.Covered[0] != "example.com/st" {
+		t.Errorf("covered = %v, want [example.com/st]", report.Covered)
+	}
+}
@@ -0,0 +1,176 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Backend writes an abstractive summary of one session, keeping the "why"
+// that first-line extraction drops.
+type Backend interface {
+	// Name identifies the backend and model; it is part of the cache key.
+	Name() string
+	SummarizeSession(entries []Entry) (string, error)
+}
+
+const (
+	APIOpenAI = "openai"
+	APIOllama = "ollama"
+)
+
+// LLMConfig points the LLM backend at an OpenAI-compatible or Ollama chat
+// endpoint.
+type LLMConfig struct {
+	API     string // openai or ollama
+	BaseURL string // e.g. http://localhost:11434 or http://localhost:8080/v1
+	Model   string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// DefaultBaseURL returns the usual address of each API.
+func DefaultBaseURL(api string) string {
+	if api == APIOllama {
+		return "http://localhost:11434"
+	}
+	return "https://api.openai.com/v1"
+}
+
+// DefaultLLMTimeout bounds each summary request unless llm.timeout says
+// otherwise. Connecting gets much less, so an endpoint that isn't running
+// fails fast.
+const (
+	DefaultLLMTimeout = 30 * time.Second
+	llmConnectTimeout = 3 * time.Second
+)
+
+type LLMBackend struct {
+	cfg    LLMConfig
+	client *http.Client
+}
+
+func NewLLMBackend(cfg LLMConfig) (*LLMBackend, error) {
+	if cfg.API != APIOpenAI && cfg.API != APIOllama {
+		return nil, fmt.Errorf("unknown LLM API %q — use openai or ollama", cfg.API)
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("no LLM model configured")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL(cfg.API)
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultLLMTimeout
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: llmConnectTimeout}).DialContext,
+		TLSHandshakeTimeout:   llmConnectTimeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+	}
+	return &LLMBackend{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout, Transport: transport}}, nil
+}
+
+func (b *LLMBackend) Name() string {
+	return b.cfg.API + ":" + b.cfg.Model
+}
+
+const summaryInstructions = `You summarize one session of a software project's development history for a teammate who will pick up the work. ` +
+	`In 2 to 4 sentences of plain text, say what was decided and why, what changed, and what was left unfinished. ` +
+	`Keep file names, commands and error messages exact. Do not add a preamble.`
+
+// maxTranscriptChars keeps the request within small local models' context.
+const maxTranscriptChars = 12000
+
+func (b *LLMBackend) SummarizeSession(entries []Entry) (string, error) {
+	messages := []chatMessage{
+		{Role: "system", Content: summaryInstructions},
+		{Role: "user", Content: sessionTranscript(entries, maxTranscriptChars)},
+	}
+
+	var url string
+	var body any
+	if b.cfg.API == APIOllama {
+		url = b.cfg.BaseURL + "/api/chat"
+		body = map[string]any{"model": b.cfg.Model, "messages": messages, "stream": false, "options": map[string]any{"temperature": 0.2}}
+	} else {
+		url = b.cfg.BaseURL + "/chat/completions"
+		body = map[string]any{"model": b.cfg.Model, "messages": messages, "temperature": 0.2}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.cfg.APIKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return "", fmt.Errorf("%s did not answer within %s (raise it with 'ctxsave llm set --timeout')", url, b.cfg.Timeout)
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s: %s", url, resp.Status, truncateLine(string(raw), 200))
+	}
+
+	var out struct {
+		Message chatMessage `json:"message"` // ollama
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"` // openai
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", fmt.Errorf("parse response: %w", err)
+	}
+	text := out.Message.Content
+	if len(out.Choices) > 0 {
+		text = out.Choices[0].Message.Content
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return "", fmt.Errorf("%s returned an empty summary", url)
+	}
+	return text, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// sessionTranscript lays out a session's entries for the model, cutting
+// long entries short so one pasted log can't crowd out the rest.
+func sessionTranscript(entries []Entry, limit int) string {
+	var sb strings.Builder
+	for _, e := range entries {
+		line := fmt.Sprintf("[%s] %s\n\n", e.Type, truncateLine(strings.TrimSpace(e.Content), 1500))
+		if sb.Len()+len(line) > limit {
+			break
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
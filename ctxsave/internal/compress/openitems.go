@@ -0,0 +1,190 @@
+package compress
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ctxsave/internal/similarity"
+	"ctxsave/internal/store"
+)
+
+var nextStepPhrases = []string{
+	"next we should", "next, we should", "next step", "next steps", "we still need",
+	"still need to", "still needs", "left to do", "remaining work", "follow-up",
+	"follow up on", "we should also", "todo:", "not yet implemented", "haven't yet",
+}
+
+// minAnswerLen is how long an assistant reply must be to count as a
+// substantive answer to the user question before it.
+const minAnswerLen = 200
+
+// findOpenItems collects the threads left unfinished in the captured history:
+// unanswered user questions, assistant next-step statements, unresolved
+// errors, and TODO/FIXME lines added in captured diffs.
+func (s *Summarizer) findOpenItems(entries []Entry) []string {
+	seen := similarity.NewDeduper()
+	var items []string
+	add := func(kind, text string) {
+		text = truncateLine(strings.TrimSpace(text), 150)
+		if text == "" || seen.Seen(text) {
+			return
+		}
+		items = append(items, fmt.Sprintf("%s: %s", kind, text))
+	}
+
+	for _, q := range s.unansweredQuestions(entries) {
+		add("Unanswered question", q)
+	}
+
+	for _, e := range entries {
+		switch e.Type {
+		case store.EntryConversation, store.EntryDecision:
+			if !strings.Contains(e.Metadata, `"role":"assistant"`) {
+				continue
+			}
+			if step := nextStepSentence(e.Content); step != "" {
+				add("Next step", step)
+			}
+		case store.EntryError:
+			if parseErrorMeta(e).Status == "open" {
+				add("Unresolved error", firstLine(e.Content))
+			}
+		case store.EntryGitDiff:
+			for _, todo := range diffTodos(e) {
+				add("TODO", todo)
+			}
+		}
+	}
+
+	return items
+}
+
+// unansweredQuestions returns user questions that were not followed by a
+// substantive assistant reply before the next user turn in the same session.
+func (s *Summarizer) unansweredQuestions(entries []Entry) []string {
+	var questions []string
+	pending := ""
+	session := ""
+
+	flush := func() {
+		if pending != "" {
+			questions = append(questions, pending)
+		}
+		pending = ""
+	}
+
+	for _, e := range entries {
+		if e.SessionID != session {
+			flush()
+			session = e.SessionID
+		}
+		if e.Type != store.EntryConversation && e.Type != store.EntryDecision {
+			continue
+		}
+
+		switch {
+		case strings.Contains(e.Metadata, `"role":"user"`):
+			flush()
+			line := s.cleanQuestionLine(e.Content)
+			if strings.Contains(line, "?") {
+				pending = line
+			}
+		case strings.Contains(e.Metadata, `"role":"assistant"`):
+			if len(e.Content) >= minAnswerLen {
+				pending = ""
+			}
+		}
+	}
+	flush()
+
+	return questions
+}
+
+// nextStepSentence returns the sentence carrying a "what's next" phrase, if
+// any. Sentences end where splitLine says, so "update main.go to ..." stays
+// whole.
+func nextStepSentence(s string) string {
+	var sentences []string
+	for _, line := range strings.Split(s, "\n") {
+		sentences = append(sentences, splitLine(strings.TrimSpace(line))...)
+	}
+	for _, p := range nextStepPhrases {
+		for _, sentence := range sentences {
+			if strings.Contains(strings.ToLower(sentence), p) {
+				return sentence
+			}
+		}
+	}
+	return ""
+}
+
+// diffTodos pulls TODO/FIXME lines from git diff entries: either the
+// pre-extracted "todos" entry or added lines in raw diff text. Lines that
+// 'capture git' has since found gone from the working tree are recorded as
+// resolved in the entry's metadata and left out.
+func diffTodos(e Entry) []string {
+	var todos []string
+	isTodoList := strings.Contains(e.Metadata, `"type":"todos"`)
+	resolved := make(map[string]bool)
+	if isTodoList {
+		var meta struct {
+			Resolved []string `json:"resolved"`
+		}
+		_ = json.Unmarshal([]byte(e.Metadata), &meta)
+		for _, r := range meta.Resolved {
+			resolved[r] = true
+		}
+	}
+	for _, line := range strings.Split(e.Content, "\n") {
+		if !isTodoList {
+			if !strings.HasPrefix(line, "+") || strings.HasPrefix(line, "+++") {
+				continue
+			}
+			line = line[1:]
+		}
+		if !strings.Contains(line, "TODO") && !strings.Contains(line, "FIXME") {
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if resolved[line] {
+			continue
+		}
+		todos = append(todos, line)
+	}
+	return todos
+}
+
+// openItemsSections renders the open items for each summary level, most
+// detailed first. Levels get an empty string when nothing is open.
+func openItemsSections(items []string) map[string]string {
+	if len(items) == 0 {
+		return map[string]string{}
+	}
+
+	var detailed strings.Builder
+	detailed.WriteString("### Open Items / Next Steps\n")
+	for i, item := range items {
+		if i >= 15 {
+			break
+		}
+		detailed.WriteString(fmt.Sprintf("- %s\n", item))
+	}
+	detailed.WriteString("\n")
+
+	compressed := items
+	if len(compressed) > 5 {
+		compressed = compressed[:5]
+	}
+	ultra := items
+	if len(ultra) > 3 {
+		ultra = ultra[:3]
+	}
+
+	return map[string]string{
+		LevelRaw:        detailed.String(),
+		LevelDetailed:   detailed.String(),
+		LevelCompressed: fmt.Sprintf("**Open Items:** %s\n\n", strings.Join(compressed, "; ")),
+		LevelUltra:      fmt.Sprintf("Open: %s\n", strings.Join(ultra, "; ")),
+	}
+}
@@ -1,9 +1,13 @@
 package compress
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
+	"ctxsave/internal/rules"
+	"ctxsave/internal/similarity"
 	"ctxsave/internal/store"
 )
 
@@ -14,19 +18,39 @@ const (
 	LevelUltra      = "ultra"
 )
 
-type Summarizer struct{}
+type Summarizer struct {
+	rules *rules.Set
+}
 
 func NewSummarizer() *Summarizer {
-	return &Summarizer{}
+	return &Summarizer{rules: rules.Default()}
+}
+
+// SetRules replaces the built-in rules, whose code indicators decide which
+// lines of a user message are code rather than a question.
+func (s *Summarizer) SetRules(set *rules.Set) {
+	s.rules = set
 }
 
 func (s *Summarizer) Summarize(entries []Entry) map[string]string {
-	return map[string]string{
+	return s.SummarizeWith(entries, nil)
+}
+
+// SummarizeWith builds every level with the open items on top, followed by
+// the given per-level sections, followed by the summarized entries.
+func (s *Summarizer) SummarizeWith(entries []Entry, sections map[string]string) map[string]string {
+	entries = dropStaleTestFailures(entries)
+	open := openItemsSections(s.findOpenItems(entries))
+	levels := map[string]string{
 		LevelRaw:        s.buildRaw(entries),
 		LevelDetailed:   s.buildDetailed(entries),
 		LevelCompressed: s.buildCompressed(entries),
 		LevelUltra:      s.buildUltra(entries),
 	}
+	for lvl, body := range levels {
+		levels[lvl] = open[lvl] + sections[lvl] + body
+	}
+	return levels
 }
 
 func (s *Summarizer) BestFit(summaries map[string]string, budget int, family ModelFamily) (string, string) {
@@ -45,11 +69,20 @@ type Entry = store.Entry
 
 func (s *Summarizer) buildRaw(entries []Entry) string {
 	var sb strings.Builder
+	sb.WriteString("### Captured Entries\n")
 	for _, e := range entries {
 		content := e.Content
 		if len(content) > 500 {
 			content = content[:500] + "..."
 		}
+		if e.Type == store.EntryError {
+			switch m := parseErrorMeta(e); m.Status {
+			case "resolved":
+				content += " → " + describeResolution(e)
+			case "open":
+				content += " → still open"
+			}
+		}
 		sb.WriteString(fmt.Sprintf("[%s] %s\n\n", e.Type, content))
 	}
 	return sb.String()
@@ -60,17 +93,22 @@ func (s *Summarizer) buildDetailed(entries []Entry) string {
 	var sb strings.Builder
 
 	if items, ok := grouped[store.EntryDecision]; ok {
-		sb.WriteString("### Key Decisions & Findings\n")
-		seen := make(map[string]bool)
+		var ds []string
+		seen := similarity.NewDeduper()
 		for _, e := range items {
-			summary := extractMeaningfulLine(e.Content)
-			if summary == "" || seen[summary] {
+			summary := keySentences(e.Content, 3, 300)
+			if summary == "" || seen.Seen(summary) {
 				continue
 			}
-			seen[summary] = true
-			sb.WriteString(fmt.Sprintf("- %s\n", summary))
+			ds = append(ds, summary)
+		}
+		if len(ds) > 0 {
+			sb.WriteString("### Key Decisions & Findings\n")
+			for _, d := range ds {
+				sb.WriteString(fmt.Sprintf("- %s\n", d))
+			}
+			sb.WriteString("\n")
 		}
-		sb.WriteString("\n")
 	}
 
 	if items, ok := grouped[store.EntryCodeChange]; ok {
@@ -119,17 +157,51 @@ func (s *Summarizer) buildDetailed(entries []Entry) string {
 		sb.WriteString("\n")
 	}
 
+	if state := latestGitDiff(grouped[store.EntryGitDiff], "state"); state != nil {
+		sb.WriteString("### Git Working State\n")
+		for _, line := range strings.Split(state.Content, "\n") {
+			sb.WriteString(fmt.Sprintf("- %s\n", line))
+		}
+		sb.WriteString("\n")
+	}
+
+	if hot := latestGitDiff(grouped[store.EntryGitDiff], "hotspots"); hot != nil {
+		sb.WriteString("### Hot Areas of the Codebase\n")
+		for _, line := range strings.Split(hot.Content, "\n") {
+			sb.WriteString(fmt.Sprintf("- %s\n", line))
+		}
+		sb.WriteString("\n")
+	}
+
+	if items, ok := grouped[store.EntryTestResult]; ok {
+		sb.WriteString("### Test Results\n")
+		for i, e := range newestFirst(items) {
+			if i >= 3 {
+				break
+			}
+			sb.WriteString(fmt.Sprintf("- %s\n", firstLine(e.Content)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if items, ok := grouped[store.EntryCommand]; ok {
+		sb.WriteString("### Commands Run\n")
+		for _, c := range uniqueCommands(items, 15) {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", c))
+		}
+		sb.WriteString("\n")
+	}
+
 	if items, ok := grouped[store.EntryConversation]; ok {
 		userQuestions := filterByMeta(items, "user")
 		if len(userQuestions) > 0 {
 			sb.WriteString("### Questions Discussed\n")
-			seen := make(map[string]bool)
+			seen := similarity.NewDeduper()
 			for _, e := range userQuestions {
-				line := cleanQuestionLine(e.Content)
-				if line == "" || len(line) < 15 || seen[line] {
+				line := s.cleanQuestionLine(e.Content)
+				if line == "" || len(line) < 15 || seen.Seen(line) {
 					continue
 				}
-				seen[line] = true
 				sb.WriteString(fmt.Sprintf("- %s\n", truncateLine(line, 150)))
 			}
 			sb.WriteString("\n")
@@ -146,24 +218,47 @@ func (s *Summarizer) buildDetailed(entries []Entry) string {
 
 	if items, ok := grouped[store.EntryFile]; ok {
 		sb.WriteString("### Files Captured\n")
+		seen := make(map[string]bool)
 		for _, e := range items {
+			// chunked docs are listed once, by their first chunk
+			var m struct {
+				Path string `json:"path"`
+			}
+			_ = json.Unmarshal([]byte(e.Metadata), &m)
+			if m.Path != "" && seen[m.Path] {
+				continue
+			}
+			seen[m.Path] = true
 			sb.WriteString(fmt.Sprintf("- %s\n", firstLine(e.Content)))
 		}
 		sb.WriteString("\n")
 	}
 
 	if items, ok := grouped[store.EntryError]; ok {
-		sb.WriteString("### Errors Encountered\n")
-		seen := make(map[string]bool)
-		for _, e := range items {
-			line := firstLine(e.Content)
-			if seen[line] {
-				continue
+		// open errors are listed under Open Items / Next Steps
+		resolved, _, untracked := splitErrors(items)
+
+		if len(resolved) > 0 {
+			sb.WriteString("### Resolved Errors\n")
+			seen := make(map[string]bool)
+			for _, e := range resolved {
+				line := truncateLine(firstLine(e.Content), 150)
+				if seen[line] {
+					continue
+				}
+				seen[line] = true
+				sb.WriteString(fmt.Sprintf("- %s → %s\n", line, describeResolution(e)))
 			}
-			seen[line] = true
-			sb.WriteString(fmt.Sprintf("- %s\n", truncateLine(line, 150)))
+			sb.WriteString("\n")
+		}
+
+		if len(untracked) > 0 {
+			sb.WriteString("### Errors Encountered\n")
+			for _, line := range uniqueErrorLines(untracked, 150) {
+				sb.WriteString(fmt.Sprintf("- %s\n", line))
+			}
+			sb.WriteString("\n")
 		}
-		sb.WriteString("\n")
 	}
 
 	return sb.String()
@@ -174,22 +269,21 @@ func (s *Summarizer) buildCompressed(entries []Entry) string {
 	var sb strings.Builder
 
 	if items, ok := grouped[store.EntryDecision]; ok {
-		sb.WriteString("**Key Findings:** ")
 		var ds []string
-		seen := make(map[string]bool)
+		seen := similarity.NewDeduper()
 		for _, e := range items {
-			line := extractMeaningfulLine(e.Content)
-			if line == "" || seen[line] {
+			line := keySentences(e.Content, 1, 150)
+			if line == "" || seen.Seen(line) {
 				continue
 			}
-			seen[line] = true
 			ds = append(ds, truncateLine(line, 100))
 			if len(ds) >= 5 {
 				break
 			}
 		}
-		sb.WriteString(strings.Join(ds, "; "))
-		sb.WriteString("\n\n")
+		if len(ds) > 0 {
+			sb.WriteString("**Key Findings:** " + strings.Join(ds, "; ") + "\n\n")
+		}
 	}
 
 	if items, ok := grouped[store.EntryCodeChange]; ok {
@@ -209,6 +303,33 @@ func (s *Summarizer) buildCompressed(entries []Entry) string {
 		sb.WriteString("\n\n")
 	}
 
+	if hot := latestGitDiff(grouped[store.EntryGitDiff], "hotspots"); hot != nil {
+		for _, line := range strings.Split(hot.Content, "\n") {
+			if dirs, ok := strings.CutPrefix(line, "Hot directories: "); ok {
+				sb.WriteString(fmt.Sprintf("**Hot Areas:** %s\n\n", dirs))
+			}
+		}
+	}
+
+	if state := latestGitDiff(grouped[store.EntryGitDiff], "state"); state != nil {
+		sb.WriteString(fmt.Sprintf("**Branch:** %s", strings.TrimPrefix(firstLine(state.Content), "Branch: ")))
+		if strings.Contains(state.Content, "\nIn progress: ") {
+			sb.WriteString(" — " + strings.ToLower(strings.Split(state.Content, "\n")[1]))
+		}
+		sb.WriteString("\n\n")
+	}
+
+	if items, ok := grouped[store.EntryTestResult]; ok {
+		sb.WriteString(fmt.Sprintf("**Tests:** %s", firstLine(newestFirst(items)[0].Content)))
+		sb.WriteString("\n\n")
+	}
+
+	if items, ok := grouped[store.EntryCommand]; ok {
+		cmds := uniqueCommands(items, 5)
+		sb.WriteString(fmt.Sprintf("**Commands:** %d run — `%s`", len(items), strings.Join(cmds, "`, `")))
+		sb.WriteString("\n\n")
+	}
+
 	if items, ok := grouped[store.EntryNote]; ok {
 		sb.WriteString("**Notes:** ")
 		var ns []string
@@ -227,7 +348,14 @@ func (s *Summarizer) buildUltra(entries []Entry) string {
 
 	var parts []string
 	if items, ok := grouped[store.EntryDecision]; ok {
-		parts = append(parts, fmt.Sprintf("%d decisions", len(items)))
+		seen := similarity.NewDeduper()
+		count := 0
+		for _, e := range items {
+			if !seen.Seen(e.Content) {
+				count++
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%d decisions", count))
 	}
 	if items, ok := grouped[store.EntryCodeChange]; ok {
 		edits := filterEdits(items)
@@ -239,6 +367,15 @@ func (s *Summarizer) buildUltra(entries []Entry) string {
 	if items, ok := grouped[store.EntryNote]; ok {
 		parts = append(parts, fmt.Sprintf("%d notes", len(items)))
 	}
+	if items, ok := grouped[store.EntryCommand]; ok {
+		parts = append(parts, fmt.Sprintf("%d commands", len(items)))
+	}
+	if items, ok := grouped[store.EntryError]; ok {
+		_, open, _ := splitErrors(items)
+		if n := len(uniqueErrorLines(open, 150)); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d open errors", n))
+		}
+	}
 
 	files := extractEditedFiles(entries)
 	filePart := ""
@@ -275,32 +412,6 @@ func truncateLine(s string, max int) string {
 	return s[:max] + "..."
 }
 
-// extractMeaningfulLine finds the first non-trivial line from content that conveys actual information.
-func extractMeaningfulLine(s string) string {
-	lines := strings.Split(s, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		if len(line) < 20 {
-			continue
-		}
-		lower := strings.ToLower(line)
-		if strings.HasPrefix(lower, "now ") || strings.HasPrefix(lower, "let me ") ||
-			strings.HasPrefix(lower, "good") || strings.HasPrefix(lower, "here's") ||
-			strings.HasPrefix(lower, "okay") {
-			continue
-		}
-		return truncateLine(line, 200)
-	}
-	fl := firstLine(s)
-	if len(fl) > 20 {
-		return truncateLine(fl, 200)
-	}
-	return ""
-}
-
 func filterByMeta(entries []Entry, role string) []Entry {
 	needle := fmt.Sprintf(`"role":"%s"`, role)
 	var result []Entry
@@ -360,14 +471,14 @@ func filterSearches(entries []Entry) []string {
 }
 
 // cleanQuestionLine extracts a clean question from user content, skipping code blocks.
-func cleanQuestionLine(s string) string {
-	lines := strings.Split(s, "\n")
+func (s *Summarizer) cleanQuestionLine(text string) string {
+	lines := strings.Split(text, "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" || len(line) < 15 {
 			continue
 		}
-		if looksLikeCode(line) {
+		if s.looksLikeCode(line) {
 			continue
 		}
 		if strings.HasPrefix(line, "@") {
@@ -381,8 +492,8 @@ func cleanQuestionLine(s string) string {
 	return ""
 }
 
-func looksLikeCode(s string) bool {
-	trimmed := strings.TrimSpace(s)
+func (s *Summarizer) looksLikeCode(line string) bool {
+	trimmed := strings.TrimSpace(line)
 
 	if strings.HasSuffix(trimmed, ";") || strings.HasSuffix(trimmed, "{") ||
 		strings.HasSuffix(trimmed, "}") || strings.HasSuffix(trimmed, "},") ||
@@ -395,21 +506,8 @@ func looksLikeCode(s string) bool {
 		return true
 	}
 
-	codeIndicators := []string{
-		"const ", "let ", "var ", "function ", "async ", "await ",
-		"if (", "if(", "} else", "=>", "===", "!==",
-		".find(", ".filter(", ".map(", ".push(", ".select(",
-		"new ObjectId(", "new mongoose.", "req.", "res.",
-		"L1:", "L2:", "L3:", "L4:", "L5:", "L6:", "L7:", "L8:", "L9:",
-		"return (", "return {", "return [",
-		"success:", "message:", "resType:",
-		"sfsrtlp0141@",
-		"**Project:**",
-	}
-	for _, ind := range codeIndicators {
-		if strings.Contains(s, ind) {
-			return true
-		}
+	if s.rules.LooksLikeCode(line) {
+		return true
 	}
 
 	// Property assignment: "key: value," or "key: value"
@@ -438,6 +536,157 @@ func looksLikeCode(s string) bool {
 	return false
 }
 
+// dropStaleTestFailures drops a test failure once a later test run covered
+// its package or suite, so tests that have passed since don't linger as
+// open problems. Failures in packages no later run included stay open.
+// Runs are ordered by when they were captured, not by position, since
+// pinned sessions come first in entries.
+func dropStaleTestFailures(entries []Entry) []Entry {
+	var runs []Entry
+	for _, e := range entries {
+		if e.Type == store.EntryTestResult {
+			runs = append(runs, e)
+		}
+	}
+
+	var kept []Entry
+	for _, e := range entries {
+		if e.Type == store.EntryError && isTestFailure(e) && rerunSince(e, runs) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+// rerunSince reports whether a run captured after the failure covered its
+// package. Runs captured before packages were recorded cover everything.
+func rerunSince(failure Entry, runs []Entry) bool {
+	var fm struct {
+		Package string `json:"package"`
+	}
+	_ = json.Unmarshal([]byte(failure.Metadata), &fm)
+	for _, r := range runs {
+		if r.SessionID == failure.SessionID || !r.CreatedAt.After(failure.CreatedAt) {
+			continue
+		}
+		var rm struct {
+			Packages []string `json:"packages"`
+		}
+		_ = json.Unmarshal([]byte(r.Metadata), &rm)
+		if rm.Packages == nil {
+			return true
+		}
+		for _, p := range rm.Packages {
+			if p == fm.Package {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newestFirst returns entries ordered by when they were captured, most
+// recent first.
+func newestFirst(entries []Entry) []Entry {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+	return sorted
+}
+
+func isTestFailure(e Entry) bool {
+	return strings.Contains(e.Metadata, `"source":"go test"`) || strings.Contains(e.Metadata, `"source":"junit"`)
+}
+
+// uniqueCommands returns up to max distinct single-line commands, keeping
+// the order they were run in.
+func uniqueCommands(entries []Entry, max int) []string {
+	seen := make(map[string]bool)
+	var cmds []string
+	for _, e := range entries {
+		c := truncateLine(strings.ReplaceAll(firstLine(e.Content), "`", "'"), 120)
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		cmds = append(cmds, c)
+		if len(cmds) >= max {
+			break
+		}
+	}
+	return cmds
+}
+
+type errorMeta struct {
+	Status     string `json:"status"`
+	Resolution string `json:"resolution"`
+	ResolvedBy string `json:"resolved_by"`
+}
+
+// latestGitDiff returns the most recently captured git_diff entry of the
+// given kind ("state", "hotspots").
+func latestGitDiff(diffs []Entry, kind string) *Entry {
+	var latest *Entry
+	for i := range diffs {
+		if !strings.Contains(diffs[i].Metadata, `"type":"`+kind+`"`) {
+			continue
+		}
+		if latest == nil || diffs[i].CreatedAt.After(latest.CreatedAt) {
+			latest = &diffs[i]
+		}
+	}
+	return latest
+}
+
+func parseErrorMeta(e Entry) errorMeta {
+	var m errorMeta
+	_ = json.Unmarshal([]byte(e.Metadata), &m)
+	return m
+}
+
+// splitErrors sorts errors by the resolution status recorded at capture time.
+// Errors captured before tracking existed carry no status and are returned as untracked.
+func splitErrors(entries []Entry) (resolved, open, untracked []Entry) {
+	for _, e := range entries {
+		switch parseErrorMeta(e).Status {
+		case "resolved":
+			resolved = append(resolved, e)
+		case "open":
+			open = append(open, e)
+		case "repeated":
+			// a later occurrence of the same error carries the outcome
+		default:
+			untracked = append(untracked, e)
+		}
+	}
+	return resolved, open, untracked
+}
+
+func describeResolution(e Entry) string {
+	m := parseErrorMeta(e)
+	switch m.Resolution {
+	case "edit":
+		return "fixed by " + m.ResolvedBy
+	case "possible_edit":
+		return "possibly fixed by " + m.ResolvedBy
+	}
+	return "resolved: " + m.ResolvedBy
+}
+
+func uniqueErrorLines(entries []Entry, max int) []string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, e := range entries {
+		line := truncateLine(firstLine(e.Content), max)
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 // extractEditedFiles pulls unique shortened file paths from code change entries.
 func extractEditedFiles(entries []Entry) []string {
 	seen := make(map[string]bool)
@@ -0,0 +1,204 @@
+package compress
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ctxsave/internal/similarity"
+)
+
+// fillerOpeners start sentences that narrate rather than inform.
+var fillerOpeners = []string{"now ", "let me ", "good", "great", "here's", "okay", "ok,", "sure", "perfect", "alright"}
+
+// abbreviations end in a period without ending the sentence.
+var abbreviations = map[string]bool{
+	"e.g.": true, "i.e.": true, "etc.": true, "vs.": true, "cf.": true, "approx.": true, "mr.": true, "dr.": true,
+}
+
+// splitSentences breaks text into sentences. Code blocks are skipped,
+// and list items and headings count as sentences of their own.
+func splitSentences(text string) []string {
+	var sentences []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || line == "" {
+			continue
+		}
+		line = strings.TrimLeft(line, "#>*-+ ")
+		sentences = append(sentences, splitLine(line)...)
+	}
+	return sentences
+}
+
+// splitLine splits one line at sentence-ending punctuation followed by a
+// space and a capital, digit or quote, so "main.go" and "v1.2" stay whole.
+func splitLine(line string) []string {
+	var sentences []string
+	runes := []rune(line)
+	start := 0
+	for i := 0; i < len(runes)-2; i++ {
+		if runes[i] != '.' && runes[i] != '!' && runes[i] != '?' {
+			continue
+		}
+		if runes[i+1] != ' ' {
+			continue
+		}
+		next := runes[i+2]
+		if !unicode.IsUpper(next) && !unicode.IsDigit(next) && next != '`' && next != '"' && next != '(' {
+			continue
+		}
+		fields := strings.Fields(string(runes[start : i+1]))
+		if len(fields) > 0 && abbreviations[strings.ToLower(fields[len(fields)-1])] {
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = i + 2
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+func isFiller(sentence string) bool {
+	lower := strings.ToLower(sentence)
+	for _, p := range fillerOpeners {
+		if strings.HasPrefix(lower, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidateSentences drops short sentences and filler, falling back to
+// the filler when nothing else is left, so that a decision made of a
+// single "Good catch, …" sentence still has something to show.
+func candidateSentences(text string) []string {
+	var long, informative []string
+	for _, s := range splitSentences(text) {
+		if len(s) < 20 {
+			continue
+		}
+		long = append(long, s)
+		if !isFiller(s) {
+			informative = append(informative, s)
+		}
+	}
+	if len(informative) > 0 {
+		return informative
+	}
+	return long
+}
+
+// rankSentences scores sentences with TextRank: each sentence is a node,
+// edges are weighted by shared words normalized for length, and a
+// sentence scores high when it shares words with other high scorers.
+func rankSentences(sentences []string) []float64 {
+	n := len(sentences)
+	tokens := make([]map[string]bool, n)
+	for i, s := range sentences {
+		tokens[i] = make(map[string]bool)
+		for _, t := range similarity.Tokens(s) {
+			tokens[i][t] = true
+		}
+	}
+
+	weights := make([][]float64, n)
+	outSum := make([]float64, n)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if len(tokens[i]) < 2 || len(tokens[j]) < 2 {
+				continue
+			}
+			shared := 0
+			for t := range tokens[i] {
+				if tokens[j][t] {
+					shared++
+				}
+			}
+			if shared == 0 {
+				continue
+			}
+			w := float64(shared) / (math.Log(float64(len(tokens[i]))) + math.Log(float64(len(tokens[j]))))
+			weights[i][j], weights[j][i] = w, w
+			outSum[i] += w
+			outSum[j] += w
+		}
+	}
+
+	const damping = 0.85
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iter := 0; iter < 50; iter++ {
+		next := make([]float64, n)
+		delta := 0.0
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				if weights[j][i] > 0 {
+					sum += weights[j][i] / outSum[j] * scores[j]
+				}
+			}
+			next[i] = (1 - damping) + damping*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+		scores = next
+		if delta < 1e-4 {
+			break
+		}
+	}
+	return scores
+}
+
+// keySentences returns the most central sentences of text, at most max of
+// them and within limit characters, in their original order. Filler such
+// as "Now let me check…" is never picked while there is anything else.
+func keySentences(text string, max, limit int) string {
+	candidates := candidateSentences(text)
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	scores := rankSentences(candidates)
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	var picked []int
+	length := 0
+	for _, i := range order {
+		if len(picked) >= max {
+			break
+		}
+		if len(picked) > 0 && length+len(candidates[i]) > limit {
+			continue
+		}
+		picked = append(picked, i)
+		length += len(candidates[i]) + 1
+	}
+	sort.Ints(picked)
+
+	parts := make([]string, len(picked))
+	for k, i := range picked {
+		parts[k] = candidates[i]
+	}
+	return truncateLine(strings.Join(parts, " "), limit)
+}
@@ -0,0 +1,173 @@
+package generate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"ctxsave/internal/compress"
+	"ctxsave/internal/similarity"
+	"ctxsave/internal/store"
+)
+
+// Settings that configure the LLM summarizer, written by 'ctxsave llm set'.
+const (
+	LLMAPISetting     = "llm.api"
+	LLMURLSetting     = "llm.url"
+	LLMModelSetting   = "llm.model"
+	LLMTimeoutSetting = "llm.timeout"
+)
+
+// LLMKeyEnv holds the API key, which is kept out of the database.
+const LLMKeyEnv = "CTXSAVE_LLM_API_KEY"
+
+// abstractiveLevel is the summaries level LLM summaries are cached under.
+const abstractiveLevel = "abstractive"
+
+// maxAbstractiveSessions caps how many sessions are sent to the model for
+// one briefing; older ones are covered by the extractive sections.
+const maxAbstractiveSessions = 8
+
+// LoadLLMConfig reads the LLM summarizer settings, or returns nil when no
+// model is configured.
+func LoadLLMConfig(st *store.Store) (*compress.LLMConfig, error) {
+	cfg := &compress.LLMConfig{}
+	for key, dst := range map[string]*string{
+		LLMAPISetting:   &cfg.API,
+		LLMURLSetting:   &cfg.BaseURL,
+		LLMModelSetting: &cfg.Model,
+	} {
+		value, _, err := st.GetSetting(key)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", key, err)
+		}
+		*dst = value
+	}
+	if cfg.Model == "" {
+		return nil, nil
+	}
+	timeout, _, err := st.GetSetting(LLMTimeoutSetting)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", LLMTimeoutSetting, err)
+	}
+	if timeout != "" {
+		if cfg.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, fmt.Errorf("invalid %s %q — use e.g. 30s or 2m", LLMTimeoutSetting, timeout)
+		}
+	}
+	cfg.APIKey = os.Getenv(LLMKeyEnv)
+	if cfg.APIKey == "" && cfg.API == compress.APIOpenAI {
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return cfg, nil
+}
+
+// abstractiveSections returns a "Session Summaries" section for the most
+// recent chat sessions, written by the backend and cached per session.
+// Sessions the backend can't summarize are left to the extractive levels.
+// The first failure, a timeout included, marks the endpoint unavailable
+// for the rest of the run: no further calls are made, cached summaries are
+// still used, and the failure is returned as a warning.
+func (g *PromptGenerator) abstractiveSections(backend compress.Backend, entries []store.Entry) (map[string]string, string, error) {
+	var order []string
+	bySession := make(map[string][]store.Entry)
+	for _, e := range entries {
+		if _, ok := bySession[e.SessionID]; !ok {
+			order = append(order, e.SessionID)
+		}
+		bySession[e.SessionID] = append(bySession[e.SessionID], e)
+	}
+
+	// lines[i] is the detailed line for summaries[i]; labels may hold ": "
+	var lines, summaries []string
+	var failure error
+	for _, id := range order {
+		if len(lines) >= maxAbstractiveSessions {
+			break
+		}
+		if !hasChat(bySession[id]) {
+			continue
+		}
+
+		hash := summaryInputHash(backend.Name(), bySession[id])
+		cached, err := g.store.GetCachedSummary(id, abstractiveLevel, hash)
+		if err != nil {
+			return nil, "", err
+		}
+		summary := ""
+		switch {
+		case cached != nil:
+			summary = cached.Content
+		case failure == nil:
+			summary, failure = backend.SummarizeSession(bySession[id])
+			if failure != nil {
+				continue
+			}
+			if _, err := g.store.AddSummary(id, abstractiveLevel, hash, summary, compress.EstimateTokens(summary, compress.FamilyGPT)); err != nil {
+				return nil, "", err
+			}
+		default:
+			continue
+		}
+
+		label := id
+		if sess, err := g.store.GetSession(id); err == nil {
+			label = fmt.Sprintf("%s %s", sess.CreatedAt.Local().Format("2006-01-02"), sess.Label)
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", label, summary))
+		summaries = append(summaries, summary)
+	}
+
+	warning := ""
+	if failure != nil {
+		warning = fmt.Sprintf("LLM summarizer unavailable, using extractive summaries: %v", failure)
+	}
+	if len(lines) == 0 {
+		return nil, warning, nil
+	}
+
+	var compressed []string
+	seen := similarity.NewDeduper()
+	for _, summary := range summaries {
+		if sentence := firstSentence(summary); !seen.Seen(sentence) {
+			compressed = append(compressed, sentence)
+		}
+		if len(compressed) >= 3 {
+			break
+		}
+	}
+	return map[string]string{
+		compress.LevelDetailed:   "### Session Summaries\n" + strings.Join(lines, "\n") + "\n\n",
+		compress.LevelCompressed: "**Session Summaries:** " + strings.Join(compressed, " ") + "\n\n",
+	}, warning, nil
+}
+
+func hasChat(entries []store.Entry) bool {
+	for _, e := range entries {
+		if e.Type == store.EntryConversation || e.Type == store.EntryDecision {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryInputHash identifies a session's entries as the backend saw them,
+// so edits, new entries or a different model invalidate the cache.
+func summaryInputHash(backend string, entries []store.Entry) string {
+	h := sha256.New()
+	h.Write([]byte(backend))
+	for _, e := range entries {
+		fmt.Fprintf(h, "\x00%d\x00%s\x00%s", e.ID, e.Type, e.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i > 0 {
+		return s[:i+1]
+	}
+	return s
+}
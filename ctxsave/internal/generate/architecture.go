@@ -0,0 +1,84 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"ctxsave/internal/compress"
+	"ctxsave/internal/store"
+)
+
+const maxArchitectureMembers = 30
+
+// applyStructure drops structure snapshots from the entries and returns an
+// "Architecture" section built from the newest one. Older snapshots are
+// history only; the briefing should describe the code as it is now.
+func applyStructure(entries []store.Entry, snapshots []store.Entry) ([]store.Entry, map[string]string) {
+	var kept []store.Entry
+	for _, e := range entries {
+		if e.Type != store.EntryStructure {
+			kept = append(kept, e)
+		}
+	}
+	if len(snapshots) == 0 {
+		return kept, nil
+	}
+	latest := snapshots[len(snapshots)-1]
+
+	var detailed strings.Builder
+	detailed.WriteString("### Architecture\n")
+	var units []string
+	var unit string
+	var members []string
+	flush := func() {
+		if unit == "" {
+			return
+		}
+		line := "- " + unit
+		if len(members) > 0 {
+			shown := members
+			if len(shown) > maxArchitectureMembers {
+				shown = shown[:maxArchitectureMembers]
+			}
+			line += ": " + strings.Join(shown, "; ")
+			if extra := len(members) - len(shown); extra > 0 {
+				line += fmt.Sprintf("; … +%d more", extra)
+			}
+		}
+		detailed.WriteString(line + "\n")
+		units = append(units, strings.SplitN(unit, " ", 2)[0])
+	}
+	for _, line := range strings.Split(latest.Content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "  ") {
+			members = append(members, strings.TrimSpace(line))
+			continue
+		}
+		flush()
+		unit, members = line, nil
+	}
+	flush()
+	detailed.WriteString("\n")
+
+	compact := fmt.Sprintf("**Architecture:** %s\n\n", strings.Join(units, ", "))
+
+	return kept, map[string]string{
+		compress.LevelRaw:        detailed.String(),
+		compress.LevelDetailed:   detailed.String(),
+		compress.LevelCompressed: compact,
+		compress.LevelUltra:      "",
+	}
+}
+
+// mergeSections joins per-level sections in the order given.
+func mergeSections(parts ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, p := range parts {
+		for lvl, text := range p {
+			merged[lvl] += text
+		}
+	}
+	return merged
+}
@@ -0,0 +1,129 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"ctxsave/internal/compress"
+	"ctxsave/internal/similarity"
+	"ctxsave/internal/store"
+)
+
+// restatementThreshold is the share of a decision's title or rationale
+// words a captured decision must contain to count as restating it.
+const restatementThreshold = 0.8
+
+// applyDecisionLog drops entries that back a recorded decision — superseded
+// ones must not resurface, and live ones are rendered from the log instead —
+// and returns the log section to put in front of each summary level.
+// Captured decisions that were never linked are dropped too when they
+// restate a superseded decision more closely than any live one.
+func applyDecisionLog(entries []store.Entry, decisions []store.Decision) ([]store.Entry, map[string]string) {
+	linked := make(map[int64]bool)
+	var active, superseded []store.Decision
+	for _, d := range decisions {
+		if d.EntryID > 0 {
+			linked[d.EntryID] = true
+		}
+		if d.Status == store.DecisionSuperseded {
+			superseded = append(superseded, d)
+		} else {
+			active = append(active, d)
+		}
+	}
+
+	var kept []store.Entry
+	for _, e := range entries {
+		if linked[e.ID] {
+			continue
+		}
+		if e.Type == store.EntryDecision && len(superseded) > 0 {
+			words := wordSet(e.Content)
+			old := bestRestatement(words, superseded)
+			if old >= restatementThreshold && old > bestRestatement(words, active) {
+				continue
+			}
+		}
+		kept = append(kept, e)
+	}
+
+	if len(active) == 0 {
+		return kept, nil
+	}
+
+	var detailed strings.Builder
+	detailed.WriteString("### Decision Log\n")
+	var titles []string
+	for _, d := range active {
+		line := fmt.Sprintf("- [%s] %s", d.Status, d.Title)
+		if d.Rationale != "" {
+			line += " — " + d.Rationale
+		}
+		detailed.WriteString(line + "\n")
+		titles = append(titles, d.Title)
+	}
+	detailed.WriteString("\n")
+
+	compact := fmt.Sprintf("**Decisions:** %s\n\n", strings.Join(titles, "; "))
+
+	return kept, map[string]string{
+		compress.LevelRaw:        detailed.String(),
+		compress.LevelDetailed:   detailed.String(),
+		compress.LevelCompressed: compact,
+		compress.LevelUltra:      compact,
+	}
+}
+
+// bestRestatement returns how much of the closest decision's title or
+// rationale the words cover, from 0 to 1.
+func bestRestatement(words map[string]bool, decisions []store.Decision) float64 {
+	best := 0.0
+	for _, d := range decisions {
+		for _, text := range []string{d.Title, d.Rationale} {
+			if c := coverage(stems(text), words); c > best {
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+func coverage(tokens []string, words map[string]bool) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+	found := 0
+	for _, t := range tokens {
+		if words[t] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(tokens))
+}
+
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, t := range stems(text) {
+		words[t] = true
+	}
+	return words
+}
+
+// stems reduces words to a crude stem, so "stored" matches "storing" and
+// "Postgres" matches "PostgreSQL".
+func stems(text string) []string {
+	tokens := similarity.Tokens(text)
+	for i, t := range tokens {
+		for _, suffix := range []string{"ing", "ed"} {
+			if len(t) > len(suffix)+3 && strings.HasSuffix(t, suffix) {
+				t = strings.TrimSuffix(t, suffix)
+				break
+			}
+		}
+		if r := []rune(t); len(r) > 5 {
+			t = string(r[:5])
+		}
+		tokens[i] = t
+	}
+	return tokens
+}
@@ -0,0 +1,282 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"ctxsave/internal/compress"
+)
+
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatXML      Format = "xml"
+	FormatPlain    Format = "plain"
+)
+
+// briefing holds everything a renderer needs to lay out the final prompt.
+type briefing struct {
+	Project    string
+	ModelName  string
+	Level      string
+	EntryCount int
+	Budget     int
+	Body       string
+}
+
+type section struct {
+	Title string
+	Lines []string
+}
+
+// FormatForFamily returns the layout each model family handles best:
+// XML-tagged sections for Claude, Markdown for GPT, plain text for Gemini.
+func FormatForFamily(family compress.ModelFamily) Format {
+	switch family {
+	case compress.FamilyClaude:
+		return FormatXML
+	case compress.FamilyGemini:
+		return FormatPlain
+	default:
+		return FormatMarkdown
+	}
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	case FormatXML:
+		return FormatXML, nil
+	case FormatPlain, "text":
+		return FormatPlain, nil
+	default:
+		return "", fmt.Errorf("unknown format %q — use markdown, xml, or plain", s)
+	}
+}
+
+func render(f Format, b briefing) string {
+	switch f {
+	case FormatXML:
+		return renderXML(b)
+	case FormatPlain:
+		return renderPlain(b)
+	default:
+		return renderMarkdown(b)
+	}
+}
+
+func renderMarkdown(b briefing) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("You are continuing work on the %s project. ", b.Project))
+	sb.WriteString("The briefing below summarizes the decisions, changes, and open threads from earlier sessions. ")
+	sb.WriteString("Treat it as established context and pick up where it leaves off.\n\n")
+	sb.WriteString("# Project Context Briefing\n\n")
+	sb.WriteString(fmt.Sprintf("**Project:** %s\n", b.Project))
+	sb.WriteString(fmt.Sprintf("**Target Model:** %s\n", b.ModelName))
+	sb.WriteString(fmt.Sprintf("**Compression Level:** %s (%d entries summarized)\n", b.Level, b.EntryCount))
+	sb.WriteString(fmt.Sprintf("**Token Budget:** ~%d tokens\n\n", b.Budget))
+	sb.WriteString("---\n\n")
+	sb.WriteString(b.Body)
+	sb.WriteString("\n---\n\n")
+	sb.WriteString("*This briefing was generated by ctxsave. Continue the work described above.*\n")
+
+	return sb.String()
+}
+
+func renderXML(b briefing) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("<project_context project=\"%s\" target_model=\"%s\" compression_level=\"%s\" entries=\"%d\" token_budget=\"%d\">\n",
+		escapeXML(b.Project), escapeXML(b.ModelName), escapeXML(b.Level), b.EntryCount, b.Budget))
+
+	preamble, sections := splitSections(b.Body)
+	if preamble != "" {
+		sb.WriteString("<summary>\n")
+		sb.WriteString(escapeXML(preamble))
+		sb.WriteString("\n</summary>\n")
+	}
+	for _, sec := range sections {
+		tag := sectionTag(sec.Title)
+		sb.WriteString(fmt.Sprintf("<%s>\n", tag))
+		sb.WriteString(escapeXML(strings.Join(sec.Lines, "\n")))
+		sb.WriteString(fmt.Sprintf("\n</%s>\n", tag))
+	}
+
+	sb.WriteString("</project_context>\n\n")
+	sb.WriteString("This briefing was generated by ctxsave. Continue the work described in <project_context>.\n")
+
+	return sb.String()
+}
+
+func renderPlain(b briefing) string {
+	var sb strings.Builder
+
+	sb.WriteString("PROJECT CONTEXT BRIEFING\n\n")
+	sb.WriteString(fmt.Sprintf("Project: %s\n", b.Project))
+	sb.WriteString(fmt.Sprintf("Target model: %s\n", b.ModelName))
+	sb.WriteString(fmt.Sprintf("Compression level: %s (%d entries summarized)\n", b.Level, b.EntryCount))
+	sb.WriteString(fmt.Sprintf("Token budget: about %d tokens\n\n", b.Budget))
+
+	preamble, sections := splitSections(b.Body)
+	if preamble != "" {
+		sb.WriteString(stripMarkdown(preamble))
+		sb.WriteString("\n\n")
+	}
+	for _, sec := range sections {
+		sb.WriteString(strings.ToUpper(sec.Title))
+		sb.WriteString("\n")
+		inFence := false
+		for _, line := range sec.Lines {
+			// code blocks go out as they are
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inFence = !inFence
+				sb.WriteString(line)
+			} else if inFence {
+				sb.WriteString(line)
+			} else {
+				sb.WriteString(stripMarkdown(line))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("This briefing was generated by ctxsave. Continue the work described above.\n")
+
+	return sb.String()
+}
+
+// splitSections breaks summarizer output into titled sections. Detailed
+// levels use "### Title" headings; compressed levels use "**Title:** text"
+// paragraphs. Anything before the first section is returned as the preamble.
+// Lines are kept verbatim, so code blocks keep their indentation, and
+// headings inside a code block don't start a section.
+func splitSections(body string) (string, []section) {
+	var preamble []string
+	var sections []section
+	inFence := false
+
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		} else if !inFence {
+			if strings.HasPrefix(trimmed, "### ") {
+				sections = append(sections, section{Title: strings.TrimPrefix(trimmed, "### ")})
+				continue
+			}
+			if strings.HasPrefix(trimmed, "**") {
+				if end := strings.Index(trimmed, ":**"); end > 2 {
+					sec := section{Title: trimmed[2:end]}
+					if rest := strings.TrimSpace(trimmed[end+3:]); rest != "" {
+						sec.Lines = append(sec.Lines, rest)
+					}
+					sections = append(sections, sec)
+					continue
+				}
+			}
+		}
+
+		if len(sections) == 0 {
+			preamble = append(preamble, line)
+			continue
+		}
+		cur := &sections[len(sections)-1]
+		cur.Lines = append(cur.Lines, line)
+	}
+
+	for i := range sections {
+		sections[i].Lines = trimBlankLines(sections[i].Lines)
+	}
+	return strings.Join(trimBlankLines(preamble), "\n"), sections
+}
+
+// trimBlankLines drops the blank lines around a block of text; blank lines
+// inside it are kept.
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// xmlEscaper escapes text for XML content and quoted attributes. Unlike
+// xml.EscapeText it leaves newlines and tabs alone, so code stays readable.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+var sectionTags = map[string]string{
+	"captured entries":          "entries",
+	"pinned":                    "pinned",
+	"architecture":              "architecture",
+	"session summaries":         "session_summaries",
+	"decision log":              "decision_log",
+	"decisions":                 "decision_log",
+	"key decisions & findings":  "decisions",
+	"key findings":              "decisions",
+	"files modified":            "recent_changes",
+	"code changes":              "recent_changes",
+	"git commits":               "git_history",
+	"git":                       "git_history",
+	"git working state":         "git_state",
+	"branch":                    "git_state",
+	"hot areas of the codebase": "hot_areas",
+	"hot areas":                 "hot_areas",
+	"questions discussed":       "questions",
+	"errors encountered":        "errors",
+	"resolved errors":           "resolved_errors",
+	"open items / next steps":   "open_items",
+	"open items":                "open_items",
+	"files captured":            "captured_files",
+	"files investigated":        "files_investigated",
+	"key patterns searched":     "searches",
+	"notes":                     "notes",
+	"commands run":              "commands",
+	"test results":              "test_results",
+	"tests":                     "test_results",
+	"commands":                  "commands",
+}
+
+// sectionTag maps a section title to a stable snake_case XML tag.
+func sectionTag(title string) string {
+	lower := strings.ToLower(strings.TrimSpace(title))
+	if tag, ok := sectionTags[lower]; ok {
+		return tag
+	}
+
+	var sb strings.Builder
+	underscore := false
+	for _, r := range lower {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			underscore = false
+			continue
+		}
+		if !underscore && sb.Len() > 0 {
+			sb.WriteByte('_')
+			underscore = true
+		}
+	}
+	tag := strings.TrimSuffix(sb.String(), "_")
+	if tag == "" {
+		return "section"
+	}
+	return tag
+}
+
+func stripMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "**", "")
+	s = strings.ReplaceAll(s, "`", "")
+	return s
+}
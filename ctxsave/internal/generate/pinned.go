@@ -0,0 +1,35 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"ctxsave/internal/store"
+)
+
+// applyPinned takes pinned entries out of the summarized entries and
+// returns a section holding them verbatim. It is added after whichever
+// level fits, so pinned text is never compressed away.
+func applyPinned(entries []store.Entry, pinned []store.Entry) ([]store.Entry, string) {
+	if len(pinned) == 0 {
+		return entries, ""
+	}
+
+	ids := make(map[int64]bool)
+	for _, e := range pinned {
+		ids[e.ID] = true
+	}
+	var kept []store.Entry
+	for _, e := range entries {
+		if !ids[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("### Pinned\n")
+	for _, e := range pinned {
+		sb.WriteString(fmt.Sprintf("[%s] %s\n\n", e.Type, strings.TrimSpace(e.Content)))
+	}
+	return kept, sb.String()
+}
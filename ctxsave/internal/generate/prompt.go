@@ -3,8 +3,10 @@ package generate
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"ctxsave/internal/compress"
+	"ctxsave/internal/rules"
 	"ctxsave/internal/store"
 )
 
@@ -12,6 +14,10 @@ type PromptGenerator struct {
 	store      *store.Store
 	summarizer *compress.Summarizer
 	project    string
+
+	// Warnings collects problems the last Generate worked around, such
+	// as an unreachable LLM endpoint.
+	Warnings []string
 }
 
 func NewPromptGenerator(st *store.Store, project string) *PromptGenerator {
@@ -23,17 +29,42 @@ func NewPromptGenerator(st *store.Store, project string) *PromptGenerator {
 }
 
 type GenerateOptions struct {
-	ModelKey string
-	Budget   int
-	Sessions int // how many recent sessions to include, 0 = all
+	ModelKey    string
+	Budget      int
+	Sessions    int       // how many recent sessions to include, 0 = all
+	SessionIDs  []string  // only use these sessions, nil = all
+	Sources     []string  // only use sessions from these capture sources, nil = all
+	From        time.Time // only use sessions created at or after this time
+	To          time.Time // only use sessions created before this time
+	Format      string    // layout override, "" = pick from the model family
+	Branch      string    // only use sessions captured on this git branch, "" = all
+	Tag         string    // only use entries and sessions with this tag, "" = all
+	NoLLM       bool      // skip the LLM summarizer even when one is configured
+	SessionOnly bool      // leave out project-wide pinned context, decision log and architecture
+}
+
+// selectsSessions reports whether opts pick sessions explicitly rather
+// than by branch or tag.
+func (opts GenerateOptions) selectsSessions() bool {
+	return opts.Sessions > 0 || len(opts.SessionIDs) > 0 || len(opts.Sources) > 0 || !opts.From.IsZero() || !opts.To.IsZero()
 }
 
 func (g *PromptGenerator) Generate(opts GenerateOptions) (string, error) {
+	g.Warnings = nil
 	model, ok := GetModel(opts.ModelKey)
 	if !ok {
 		return "", fmt.Errorf("unknown model %q — run 'ctxsave models' to list", opts.ModelKey)
 	}
 
+	format := FormatForFamily(model.Family)
+	if opts.Format != "" {
+		f, err := ParseFormat(opts.Format)
+		if err != nil {
+			return "", err
+		}
+		format = f
+	}
+
 	budget := opts.Budget
 	if budget <= 0 {
 		budget = defaultBudget(model)
@@ -42,36 +73,112 @@ func (g *PromptGenerator) Generate(opts GenerateOptions) (string, error) {
 		budget = model.ContextLimit / 2
 	}
 
-	entries, err := g.store.GetAllEntries(500)
+	set, err := rules.Load(g.store.Dir())
+	if err != nil {
+		return "", err
+	}
+	g.summarizer.SetRules(set)
+
+	entries, err := g.store.GetEntriesMatching(store.EntryFilter{
+		Branch:     opts.Branch,
+		Tag:        opts.Tag,
+		SessionIDs: opts.SessionIDs,
+		Sources:    opts.Sources,
+		From:       opts.From,
+		To:         opts.To,
+		Latest:     opts.Sessions,
+	}, 500)
 	if err != nil {
 		return "", fmt.Errorf("fetch entries: %w", err)
 	}
 
-	if len(entries) == 0 {
+	var pinned []store.Entry
+	if !opts.SessionOnly {
+		if pinned, err = g.store.GetPinnedEntries(); err != nil {
+			return "", fmt.Errorf("fetch pinned entries: %w", err)
+		}
+	}
+
+	if len(entries) == 0 && len(pinned) == 0 {
+		switch {
+		case opts.selectsSessions():
+			return "", fmt.Errorf("no context in the selected sessions")
+		case opts.Tag != "":
+			return "", fmt.Errorf("no context tagged %q", opts.Tag)
+		case opts.Branch != "":
+			return "", fmt.Errorf("no context captured on branch %q", opts.Branch)
+		}
 		return "", fmt.Errorf("no context captured yet — run 'ctxsave capture' first")
 	}
+	entries, pinnedText := applyPinned(entries, pinned)
 
-	summaries := g.summarizer.Summarize(entries)
-	level, content := g.summarizer.BestFit(summaries, budget, model.Family)
+	var decisionLog, architecture map[string]string
+	if opts.SessionOnly {
+		// the session's own decisions stay regular entries, and only a
+		// structure snapshot captured in it describes the architecture
+		var snapshots []store.Entry
+		for _, e := range entries {
+			if e.Type == store.EntryStructure {
+				snapshots = append(snapshots, e)
+			}
+		}
+		entries, architecture = applyStructure(entries, snapshots)
+	} else {
+		decisions, err := g.store.ListDecisions()
+		if err != nil {
+			return "", fmt.Errorf("fetch decisions: %w", err)
+		}
+		entries, decisionLog = applyDecisionLog(entries, decisions)
 
-	prompt := g.buildPrompt(model, content, level, len(entries), budget)
-	return prompt, nil
-}
+		snapshots, err := g.store.GetEntriesByType(store.EntryStructure)
+		if err != nil {
+			return "", fmt.Errorf("fetch structure: %w", err)
+		}
+		entries, architecture = applyStructure(entries, snapshots)
+	}
 
-func (g *PromptGenerator) buildPrompt(model ModelProfile, content, level string, entryCount, budget int) string {
-	var sb strings.Builder
+	var abstractive map[string]string
+	if !opts.NoLLM {
+		cfg, err := LoadLLMConfig(g.store)
+		if err != nil {
+			return "", err
+		}
+		if cfg != nil {
+			backend, err := compress.NewLLMBackend(*cfg)
+			if err != nil {
+				return "", err
+			}
+			var warning string
+			abstractive, warning, err = g.abstractiveSections(backend, entries)
+			if err != nil {
+				return "", fmt.Errorf("abstractive summaries: %w", err)
+			}
+			if warning != "" {
+				g.Warnings = append(g.Warnings, warning)
+			}
+		}
+	}
 
-	sb.WriteString("# Project Context Briefing\n\n")
-	sb.WriteString(fmt.Sprintf("**Project:** %s\n", g.project))
-	sb.WriteString(fmt.Sprintf("**Target Model:** %s\n", model.Name))
-	sb.WriteString(fmt.Sprintf("**Compression Level:** %s (%d entries summarized)\n", level, entryCount))
-	sb.WriteString(fmt.Sprintf("**Token Budget:** ~%d tokens\n\n", budget))
-	sb.WriteString("---\n\n")
-	sb.WriteString(content)
-	sb.WriteString("\n---\n\n")
-	sb.WriteString("*This briefing was generated by ctxsave. Continue the work described above.*\n")
+	// pinned text goes in at every level, so the summary gets what's left
+	summaries := g.summarizer.SummarizeWith(entries, mergeSections(architecture, decisionLog, abstractive))
+	level, content := g.summarizer.BestFit(summaries, budget-compress.EstimateTokens(pinnedText, model.Family), model.Family)
+	if pinnedText != "" {
+		content = strings.TrimRight(content, "\n") + "\n\n" + pinnedText
+	}
+
+	prompt := g.buildPrompt(format, model, content, level, len(entries)+len(pinned), budget)
+	return prompt, nil
+}
 
-	return sb.String()
+func (g *PromptGenerator) buildPrompt(format Format, model ModelProfile, content, level string, entryCount, budget int) string {
+	return render(format, briefing{
+		Project:    g.project,
+		ModelName:  model.Name,
+		Level:      level,
+		EntryCount: entryCount,
+		Budget:     budget,
+		Body:       content,
+	})
 }
 
 func defaultBudget(model ModelProfile) int {
@@ -0,0 +1,130 @@
+package generate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ctxsave/internal/compress"
+)
+
+const (
+	blockBegin = "<!-- ctxsave:begin — managed by 'ctxsave sync-rules', edits inside this block are overwritten -->"
+	blockEnd   = "<!-- ctxsave:end -->"
+)
+
+type RuleTarget struct {
+	Key  string
+	Path string // relative to the project root
+	// Header is written once when the file is first created, before the managed block.
+	Header string
+}
+
+var RuleTargets = map[string]RuleTarget{
+	"cursor": {
+		Key:    "cursor",
+		Path:   filepath.Join(".cursor", "rules", "ctxsave.mdc"),
+		Header: "---\ndescription: Project context briefing generated by ctxsave\nalwaysApply: true\n---\n",
+	},
+	"claude": {
+		Key:  "claude",
+		Path: "CLAUDE.md",
+	},
+	"agents": {
+		Key:  "agents",
+		Path: "AGENTS.md",
+	},
+	"copilot": {
+		Key:  "copilot",
+		Path: filepath.Join(".github", "copilot-instructions.md"),
+	},
+	"gemini": {
+		Key:  "gemini",
+		Path: "GEMINI.md",
+	},
+}
+
+func GetRuleTarget(key string) (RuleTarget, bool) {
+	t, ok := RuleTargets[key]
+	return t, ok
+}
+
+func RuleTargetKeys() []string {
+	var keys []string
+	for k := range RuleTargets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DefaultRuleTarget picks the rule file the given model's own agent reads.
+func DefaultRuleTarget(model ModelProfile) string {
+	switch model.Family {
+	case compress.FamilyClaude:
+		return "claude"
+	case compress.FamilyGemini:
+		return "gemini"
+	default:
+		return "agents"
+	}
+}
+
+// WriteRuleFile writes content into the managed block of the target file,
+// creating the file if needed. Text outside the block markers is preserved
+// byte for byte.
+func WriteRuleFile(projectDir string, target RuleTarget, content string) (string, error) {
+	path := filepath.Join(projectDir, target.Path)
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("read %s: %w", target.Path, err)
+	}
+
+	block := blockBegin + "\n" + strings.TrimRight(content, "\n") + "\n" + blockEnd + "\n"
+
+	var updated string
+	if os.IsNotExist(err) {
+		updated = target.Header + block
+	} else {
+		updated, err = replaceManagedBlock(string(existing), block)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", target.Path, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("create dir for %s: %w", target.Path, err)
+	}
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return "", fmt.Errorf("write %s: %w", target.Path, err)
+	}
+	return path, nil
+}
+
+func replaceManagedBlock(existing, block string) (string, error) {
+	start := strings.Index(existing, "<!-- ctxsave:begin")
+	if start < 0 {
+		if existing == "" {
+			return block, nil
+		}
+		sep := "\n"
+		if !strings.HasSuffix(existing, "\n") {
+			sep = "\n\n"
+		}
+		return existing + sep + block, nil
+	}
+
+	rel := strings.Index(existing[start:], blockEnd)
+	if rel < 0 {
+		return "", fmt.Errorf("found ctxsave begin marker without end marker — fix the file by hand")
+	}
+	end := start + rel + len(blockEnd)
+	if end < len(existing) && existing[end] == '\n' {
+		end++
+	}
+
+	return existing[:start] + block + existing[end:], nil
+}
@@ -0,0 +1,279 @@
+// Package rules loads the classification rules applied to captured chat
+// messages: which assistant messages are decisions, what is noise to drop,
+// what to redact and what to tag.
+package rules
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"ctxsave/internal/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the rules file looked up in .ctxsave/ and ~/.ctxsave/.
+const FileName = "rules.yaml"
+
+//go:embed default.yaml
+var defaultRules []byte
+
+type Action string
+
+const (
+	ActionClassify Action = "classify-as"
+	ActionDrop     Action = "drop"
+	ActionRedact   Action = "redact"
+	ActionTag      Action = "tag"
+)
+
+// Rule matches a message by role, length, keywords and a regex. A rule
+// without keywords or regex matches every message its role and length
+// limits allow; with both, either one matching is enough.
+type Rule struct {
+	Name        string   `yaml:"name"`
+	Role        string   `yaml:"role"`     // user, assistant or tool; empty matches any
+	Keywords    []string `yaml:"keywords"` // case-insensitive substrings
+	Regex       string   `yaml:"regex"`
+	MinLength   int      `yaml:"min_length"`
+	MaxLength   int      `yaml:"max_length"`
+	Action      Action   `yaml:"action"`
+	Type        string   `yaml:"type"`        // for classify-as
+	Tag         string   `yaml:"tag"`         // for tag
+	Replacement string   `yaml:"replacement"` // for redact, default "[redacted]"
+
+	re       *regexp.Regexp
+	redactRe *regexp.Regexp
+}
+
+// Set is the merged rules from every rules file, in the order they run.
+type Set struct {
+	Defaults       *bool    `yaml:"defaults"`
+	Rules          []Rule   `yaml:"rules"`
+	CodeIndicators []string `yaml:"code_indicators"`
+
+	Sources []string `yaml:"-"`
+}
+
+// Result is what the rules made of one message.
+type Result struct {
+	Type    store.EntryType
+	Content string
+	Tags    []string
+	Drop    bool
+	Matched []string // names of the rules that fired, in order
+}
+
+// Default returns the built-in rules.
+func Default() *Set {
+	set, err := parse(defaultRules, "built-in rules")
+	if err != nil {
+		panic(err)
+	}
+	set.Sources = []string{"built-in"}
+	return set
+}
+
+// Paths returns the project and global rules files, in the order their
+// rules run.
+func Paths(projectDir string) []string {
+	paths := []string{filepath.Join(projectDir, ".ctxsave", FileName)}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(homeDir, ".ctxsave", FileName))
+	}
+	return paths
+}
+
+// Load merges the project rules, the global rules and the built-in rules,
+// in that order. Files that don't exist are skipped.
+func Load(projectDir string) (*Set, error) {
+	set := &Set{}
+	useDefaults := true
+
+	for _, path := range Paths(projectDir) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		f, err := parse(data, path)
+		if err != nil {
+			return nil, err
+		}
+		set.Rules = append(set.Rules, f.Rules...)
+		set.CodeIndicators = append(set.CodeIndicators, f.CodeIndicators...)
+		set.Sources = append(set.Sources, path)
+		if f.Defaults != nil && !*f.Defaults {
+			useDefaults = false
+		}
+	}
+
+	if useDefaults {
+		d := Default()
+		set.Rules = append(set.Rules, d.Rules...)
+		set.CodeIndicators = append(set.CodeIndicators, d.CodeIndicators...)
+		set.Sources = append(set.Sources, d.Sources...)
+	}
+	return set, nil
+}
+
+func parse(data []byte, name string) (*Set, error) {
+	var set Set
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&set); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	for i := range set.Rules {
+		r := &set.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("%s: rule %q: %w", name, r.Name, err)
+		}
+	}
+	return &set, nil
+}
+
+func (r *Rule) compile() error {
+	switch r.Role {
+	case "", "user", "assistant", "tool":
+	default:
+		return fmt.Errorf("unknown role %q — use user, assistant or tool", r.Role)
+	}
+
+	for i, kw := range r.Keywords {
+		r.Keywords[i] = strings.ToLower(kw)
+	}
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		r.re = re
+	}
+
+	switch r.Action {
+	case ActionClassify:
+		if !validType(r.Type) {
+			return fmt.Errorf("classify-as needs a valid type, got %q", r.Type)
+		}
+	case ActionTag:
+		if r.Tag == "" {
+			return fmt.Errorf("tag needs a tag")
+		}
+	case ActionRedact:
+		switch {
+		case r.re != nil:
+			r.redactRe = r.re
+		case len(r.Keywords) > 0:
+			var quoted []string
+			for _, kw := range r.Keywords {
+				quoted = append(quoted, regexp.QuoteMeta(kw))
+			}
+			r.redactRe = regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+		default:
+			return fmt.Errorf("redact needs keywords or a regex")
+		}
+		if r.Replacement == "" {
+			r.Replacement = "[redacted]"
+		}
+	case ActionDrop:
+	default:
+		return fmt.Errorf("unknown action %q — use classify-as, drop, redact or tag", r.Action)
+	}
+	return nil
+}
+
+func validType(t string) bool {
+	for _, et := range store.EntryTypes {
+		if string(et) == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(role, text string) bool {
+	if r.Role != "" && r.Role != role {
+		return false
+	}
+	if len(text) < r.MinLength || (r.MaxLength > 0 && len(text) > r.MaxLength) {
+		return false
+	}
+	if len(r.Keywords) == 0 && r.re == nil {
+		return true
+	}
+	lower := strings.ToLower(text)
+	for _, kw := range r.Keywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return r.re != nil && r.re.MatchString(text)
+}
+
+// Apply runs the rules over one message. The first classify-as rule that
+// matches sets the type; redactions apply to the text later rules see.
+func (s *Set) Apply(role string, entryType store.EntryType, content string) Result {
+	res := Result{Type: entryType, Content: content}
+	classified := false
+
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		if !r.matches(role, res.Content) {
+			continue
+		}
+		switch r.Action {
+		case ActionDrop:
+			res.Drop = true
+			res.Matched = append(res.Matched, r.Name)
+			return res
+		case ActionClassify:
+			if classified {
+				continue
+			}
+			classified = true
+			res.Type = store.EntryType(r.Type)
+		case ActionRedact:
+			res.Content = r.redactRe.ReplaceAllString(res.Content, r.Replacement)
+		case ActionTag:
+			if contains(res.Tags, r.Tag) {
+				continue
+			}
+			res.Tags = append(res.Tags, r.Tag)
+		}
+		res.Matched = append(res.Matched, r.Name)
+	}
+	return res
+}
+
+// LooksLikeCode reports whether the line contains one of the code
+// indicators.
+func (s *Set) LooksLikeCode(line string) bool {
+	for _, ind := range s.CodeIndicators {
+		if strings.Contains(line, ind) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
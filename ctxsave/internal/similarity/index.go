@@ -0,0 +1,142 @@
+package similarity
+
+import (
+	"math"
+	"sort"
+
+	"ctxsave/internal/store"
+)
+
+// IndexedTypes are the entry types kept in the similarity index. Structure
+// snapshots and diffs are left out: they are large and rarely related to
+// one conversation.
+var IndexedTypes = []store.EntryType{
+	store.EntryDecision, store.EntryConversation, store.EntryNote, store.EntryError,
+	store.EntryFile, store.EntryCodeChange, store.EntryCommand, store.EntryTestResult, store.EntryGitCommit,
+}
+
+// Refresh indexes the entries added or edited since the last refresh and
+// drops the index of deleted ones. It returns how many entries it indexed.
+func Refresh(st *store.Store) (int, error) {
+	if err := st.PruneEntryIndex(); err != nil {
+		return 0, err
+	}
+	entries, err := st.UnindexedEntries(IndexedTypes)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		terms := make(map[string]int)
+		for _, t := range Tokens(e.Content) {
+			terms[t]++
+		}
+		if err := st.SaveEntryIndex(e.ID, MinHash(e.Content).Bytes(), terms); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// Index holds the TF-IDF vectors and signatures of every indexed entry.
+type Index struct {
+	vectors map[int64]map[string]float64
+	sigs    map[int64]Signature
+}
+
+// Match is an entry related to another one.
+type Match struct {
+	EntryID   int64
+	Score     float64 // cosine similarity of the TF-IDF vectors
+	Duplicate bool    // the shingles overlap enough to say the same thing
+}
+
+// Load refreshes the index and reads it into memory.
+func Load(st *store.Store) (*Index, error) {
+	if _, err := Refresh(st); err != nil {
+		return nil, err
+	}
+	terms, err := st.EntryTerms()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := st.EntrySignatures()
+	if err != nil {
+		return nil, err
+	}
+
+	df := make(map[string]int)
+	for _, counts := range terms {
+		for t := range counts {
+			df[t]++
+		}
+	}
+	n := float64(len(raw))
+
+	ix := &Index{vectors: make(map[int64]map[string]float64, len(terms)), sigs: make(map[int64]Signature, len(raw))}
+	for id, counts := range terms {
+		vec := make(map[string]float64, len(counts))
+		var norm float64
+		for t, c := range counts {
+			w := (1 + math.Log(float64(c))) * math.Log(1+n/float64(df[t]))
+			vec[t] = w
+			norm += w * w
+		}
+		norm = math.Sqrt(norm)
+		for t := range vec {
+			vec[t] /= norm
+		}
+		ix.vectors[id] = vec
+	}
+	for id, b := range raw {
+		ix.sigs[id] = DecodeSignature(b)
+	}
+	return ix, nil
+}
+
+// Related returns up to n entries most similar to the given one, scoring
+// at least minScore. Entries skip reports true for are left out.
+func (ix *Index) Related(id int64, n int, minScore float64, skip func(int64) bool) []Match {
+	vec := ix.vectors[id]
+	if len(vec) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for other, ov := range ix.vectors {
+		if other == id || (skip != nil && skip(other)) {
+			continue
+		}
+		score := cosine(vec, ov)
+		if score < minScore {
+			continue
+		}
+		matches = append(matches, Match{
+			EntryID:   other,
+			Score:     score,
+			Duplicate: ix.sigs[id].Similarity(ix.sigs[other]) >= DuplicateThreshold,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].EntryID > matches[j].EntryID
+	})
+	if len(matches) > n {
+		matches = matches[:n]
+	}
+	return matches
+}
+
+// cosine multiplies two unit vectors, iterating over the shorter one.
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot float64
+	for t, w := range a {
+		dot += w * b[t]
+	}
+	return dot
+}
@@ -0,0 +1,180 @@
+// Package similarity finds near-duplicate and related entries without any
+// model: MinHash signatures over word shingles estimate how much two texts
+// overlap, and TF-IDF vectors rank related entries.
+package similarity
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// NumHashes is the length of a MinHash signature. 64 hashes estimate the
+// Jaccard similarity to within about ±0.06.
+const NumHashes = 64
+
+// DuplicateThreshold is the estimated shingle overlap above which two
+// texts count as saying the same thing.
+const DuplicateThreshold = 0.7
+
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a an the and or but if then else so of to in on at by for with from into
+		about as is are was were be been being am do does did done have has had it its this that these those
+		there here i me my we us our you your he she they them their what which who whom how why when where
+		can could should would will shall may might must let lets just also very really please ok okay yes no
+		not now some any all each more most other such than too only own same again further once up down out
+		over under get got use used using make made need needs want like think`) {
+		stopwords[w] = true
+	}
+}
+
+// Tokens lowercases text and splits it into words, dropping stopwords,
+// single characters and a plural "s".
+func Tokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	var tokens []string
+	for _, w := range words {
+		if len(w) < 2 || stopwords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		tokens = append(tokens, w)
+	}
+	return tokens
+}
+
+// Shingles returns the overlapping word pairs of tokens. Short texts use
+// single words, where pairs would leave too few to compare.
+func Shingles(tokens []string) []string {
+	if len(tokens) < 6 {
+		return tokens
+	}
+	shingles := make([]string, 0, len(tokens)-1)
+	for i := 0; i+1 < len(tokens); i++ {
+		shingles = append(shingles, tokens[i]+" "+tokens[i+1])
+	}
+	return shingles
+}
+
+// Signature is a MinHash signature: for each of NumHashes hash functions,
+// the smallest hash of any shingle.
+type Signature []uint32
+
+// MinHash computes the signature of a text, or nil if it has no words left
+// after stopwords are dropped.
+func MinHash(text string) Signature {
+	shingles := Shingles(Tokens(text))
+	if len(shingles) == 0 {
+		return nil
+	}
+	sig := make(Signature, NumHashes)
+	for i := range sig {
+		sig[i] = ^uint32(0)
+	}
+	for _, sh := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(sh))
+		base := h.Sum64()
+		for i := range sig {
+			if v := uint32(mix(base ^ seeds[i])); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// Similarity estimates the Jaccard similarity of the shingle sets behind
+// two signatures.
+func (s Signature) Similarity(o Signature) float64 {
+	if len(s) != NumHashes || len(o) != NumHashes {
+		return 0
+	}
+	same := 0
+	for i := range s {
+		if s[i] == o[i] {
+			same++
+		}
+	}
+	return float64(same) / NumHashes
+}
+
+// Bytes encodes the signature for storage.
+func (s Signature) Bytes() []byte {
+	b := make([]byte, 4*len(s))
+	for i, v := range s {
+		binary.LittleEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// DecodeSignature reverses Bytes.
+func DecodeSignature(b []byte) Signature {
+	if len(b) != 4*NumHashes {
+		return nil
+	}
+	sig := make(Signature, NumHashes)
+	for i := range sig {
+		sig[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return sig
+}
+
+var seeds = func() [NumHashes]uint64 {
+	var s [NumHashes]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range s {
+		x += 0x9e3779b97f4a7c15
+		s[i] = mix(x)
+	}
+	return s
+}()
+
+// mix is the splitmix64 finalizer, which turns one hash into as many
+// independent-looking ones as there are seeds.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Deduper remembers the texts it has been shown and reports near
+// duplicates, which exact string comparison misses.
+type Deduper struct {
+	exact map[string]bool
+	sigs  []Signature
+}
+
+func NewDeduper() *Deduper {
+	return &Deduper{exact: make(map[string]bool)}
+}
+
+// Seen reports whether text repeats one seen before, and records it if not.
+func (d *Deduper) Seen(text string) bool {
+	if d.exact[text] {
+		return true
+	}
+	d.exact[text] = true
+
+	sig := MinHash(text)
+	if sig == nil {
+		return false
+	}
+	for _, prev := range d.sigs {
+		if sig.Similarity(prev) >= DuplicateThreshold {
+			return true
+		}
+	}
+	d.sigs = append(d.sigs, sig)
+	return false
+}
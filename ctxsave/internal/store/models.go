@@ -13,14 +13,40 @@ const (
 	EntryGitDiff      EntryType = "git_diff"
 	EntryNote         EntryType = "note"
 	EntryFile         EntryType = "file"
+	EntryCommand      EntryType = "command"
+	EntryTestResult   EntryType = "test_result"
+	EntryStructure    EntryType = "structure"
 )
 
+// EntryTypes lists every entry type, in the order they are offered when
+// an entry is reclassified.
+var EntryTypes = []EntryType{
+	EntryDecision, EntryConversation, EntryCodeChange, EntryError, EntryNote,
+	EntryFile, EntryCommand, EntryTestResult, EntryGitCommit, EntryGitDiff, EntryStructure,
+}
+
 type Session struct {
 	ID        string    `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	Source    string    `json:"source"`
 	Project  string    `json:"project"`
 	Label    string    `json:"label"`
+	Branch    string    `json:"branch"`
+	Pinned    bool      `json:"pinned"`
+	Priority  int       `json:"priority"`
+}
+
+// EntryFilter narrows the sessions entries are read from. Zero values
+// select everything. Pinned sessions pass the branch and tag filters, but
+// not an explicit session selection.
+type EntryFilter struct {
+	Branch     string
+	Tag        string
+	SessionIDs []string  // only these sessions
+	Sources    []string  // only sessions captured from these sources, e.g. "cursor"
+	From       time.Time // only sessions created at or after this time
+	To         time.Time // only sessions created before this time
+	Latest     int       // only the newest N sessions left after the other filters
 }
 
 type Entry struct {
@@ -31,13 +57,59 @@ type Entry struct {
 	Metadata  string    `json:"metadata"`
 	OrderIdx  int       `json:"order_idx"`
 	CreatedAt time.Time `json:"created_at"`
+	Pinned    bool      `json:"pinned"`
+	Priority  int       `json:"priority"`
+}
+
+// Correction records a manual change to a captured entry. Corrections are
+// keyed by the entry's captured content, so they carry over to re-captures.
+type Correction struct {
+	ID        int64     `json:"id"`
+	EntryID   int64     `json:"entry_id"`
+	Field     string    `json:"field"`
+	Original  string    `json:"original"`
+	Corrected string    `json:"corrected"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Summary struct {
 	ID           int64     `json:"id"`
 	SessionID    string    `json:"session_id"`
 	Level        string    `json:"level"`
+	InputHash    string    `json:"input_hash"`
 	Content      string    `json:"content"`
 	TokenEstimate int      `json:"token_estimate"`
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+type DecisionStatus string
+
+const (
+	DecisionProposed   DecisionStatus = "proposed"
+	DecisionAccepted   DecisionStatus = "accepted"
+	DecisionSuperseded DecisionStatus = "superseded"
+)
+
+type Decision struct {
+	ID         int64          `json:"id"`
+	Title      string         `json:"title"`
+	Rationale  string         `json:"rationale"`
+	Status     DecisionStatus `json:"status"`
+	Supersedes int64          `json:"supersedes"`
+	EntryID    int64          `json:"entry_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Document tracks a doc captured by path so re-captures can update it in place.
+type Document struct {
+	Path      string    `json:"path"`
+	Hash      string    `json:"hash"`
+	SessionID string    `json:"session_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TagCount struct {
+	Tag      string `json:"tag"`
+	Entries  int    `json:"entries"`
+	Sessions int    `json:"sessions"`
+}
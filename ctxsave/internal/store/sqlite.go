@@ -2,11 +2,13 @@ package store
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -15,6 +17,7 @@ import (
 type Store struct {
 	db      *sql.DB
 	rootDir string
+	branch  string
 }
 
 func New(projectDir string) (*Store, error) {
@@ -77,11 +80,123 @@ func (s *Store) migrate() error {
 		captured_at DATETIME NOT NULL
 	);
 
+	CREATE TABLE IF NOT EXISTS decisions (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		title      TEXT NOT NULL,
+		rationale  TEXT NOT NULL DEFAULT '',
+		status     TEXT NOT NULL,
+		supersedes INTEGER NOT NULL DEFAULT 0,
+		entry_id   INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS documents (
+		path       TEXT PRIMARY KEY,
+		hash       TEXT NOT NULL,
+		session_id TEXT NOT NULL REFERENCES sessions(id),
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS tags (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_id   INTEGER NOT NULL DEFAULT 0,
+		session_id TEXT NOT NULL DEFAULT '',
+		tag        TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS corrections (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_id    INTEGER NOT NULL,
+		source_hash TEXT NOT NULL,
+		field       TEXT NOT NULL,
+		original    TEXT NOT NULL,
+		corrected   TEXT NOT NULL,
+		created_at  DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS entry_signatures (
+		entry_id   INTEGER PRIMARY KEY,
+		minhash    BLOB NOT NULL,
+		indexed_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS entry_terms (
+		entry_id INTEGER NOT NULL,
+		term     TEXT NOT NULL,
+		count    INTEGER NOT NULL,
+		PRIMARY KEY (entry_id, term)
+	);
+
+	CREATE TABLE IF NOT EXISTS settings (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL DEFAULT ''
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_entries_session ON entries(session_id);
 	CREATE INDEX IF NOT EXISTS idx_summaries_session ON summaries(session_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_target ON tags(entry_id, session_id, tag);
+	CREATE INDEX IF NOT EXISTS idx_corrections_hash ON corrections(source_hash);
+	CREATE INDEX IF NOT EXISTS idx_entry_terms_term ON entry_terms(term);
 	`
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	columns := []struct{ table, column, def string }{
+		{"sessions", "branch", "TEXT NOT NULL DEFAULT ''"},
+		{"sessions", "pinned", "INTEGER NOT NULL DEFAULT 0"},
+		{"sessions", "priority", "INTEGER NOT NULL DEFAULT 0"},
+		{"entries", "pinned", "INTEGER NOT NULL DEFAULT 0"},
+		{"entries", "priority", "INTEGER NOT NULL DEFAULT 0"},
+		{"entries", "source_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"summaries", "input_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"sessions", "origin", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := s.addColumn(c.table, c.column, c.def); err != nil {
+			return err
+		}
+	}
+
+	// capture file used to keep its tag only in the entry metadata. Copied
+	// once, so that tags removed afterwards stay removed.
+	if _, done, err := s.GetSetting(fileTagsMigratedSetting); err != nil || done {
+		return err
+	}
+	if _, err := s.db.Exec(`INSERT OR IGNORE INTO tags (entry_id, session_id, tag, created_at)
+		SELECT id, '', json_extract(metadata, '$.tag'), created_at FROM entries
+		WHERE type = 'file' AND json_valid(metadata) AND COALESCE(json_extract(metadata, '$.tag'), '') != ''`); err != nil {
+		return fmt.Errorf("migrate file tags: %w", err)
+	}
+	return s.SetSetting(fileTagsMigratedSetting, "1")
+}
+
+const fileTagsMigratedSetting = "migrations.file_tags"
+
+// addColumn adds a column to a table created by an older version, where
+// CREATE TABLE IF NOT EXISTS leaves the existing definition alone.
+func (s *Store) addColumn(table, column, def string) error {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n); err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def)); err != nil {
+		return fmt.Errorf("add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// Dir returns the project directory the store belongs to.
+func (s *Store) Dir() string {
+	return s.rootDir
+}
+
+// SetBranch sets the git branch recorded on sessions created from now on.
+func (s *Store) SetBranch(branch string) {
+	s.branch = branch
 }
 
 func (s *Store) CreateSession(source, project, label string) (*Session, error) {
@@ -91,20 +206,41 @@ func (s *Store) CreateSession(source, project, label string) (*Session, error) {
 	}
 	now := time.Now().UTC()
 	_, err = s.db.Exec(
-		"INSERT INTO sessions (id, created_at, source, project, label) VALUES (?, ?, ?, ?, ?)",
-		id, now, source, project, label,
+		"INSERT INTO sessions (id, created_at, source, project, label, branch) VALUES (?, ?, ?, ?, ?, ?)",
+		id, now, source, project, label, s.branch,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert session: %w", err)
 	}
-	return &Session{ID: id, CreatedAt: now, Source: source, Project: project, Label: label}, nil
+	return &Session{ID: id, CreatedAt: now, Source: source, Project: project, Label: label, Branch: s.branch}, nil
+}
+
+// SetSessionOrigin records what a session was captured from, e.g. the
+// transcript path, so that a later capture of the same transcript picks up
+// the corrections made to this one.
+func (s *Store) SetSessionOrigin(id, origin string) error {
+	_, err := s.db.Exec("UPDATE sessions SET origin = ? WHERE id = ?", origin, id)
+	return err
 }
 
+// AddEntry stores a captured entry. Corrections made to the entry at the
+// same position with the same captured content in an earlier capture of
+// the same transcript are applied again, so edits and retypes survive a
+// re-capture.
 func (s *Store) AddEntry(sessionID string, entryType EntryType, content, metadata string, orderIdx int) (*Entry, error) {
 	now := time.Now().UTC()
+	var origin string
+	if err := s.db.QueryRow("SELECT origin FROM sessions WHERE id = ?", sessionID).Scan(&origin); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	hash := sourceHash(entryScope(sessionID, origin, orderIdx), content)
+	entryType, content, err := applyCorrections(s.db, hash, entryType, content)
+	if err != nil {
+		return nil, err
+	}
 	res, err := s.db.Exec(
-		"INSERT INTO entries (session_id, type, content, metadata, order_idx, created_at) VALUES (?, ?, ?, ?, ?, ?)",
-		sessionID, string(entryType), content, metadata, orderIdx, now,
+		"INSERT INTO entries (session_id, type, content, metadata, order_idx, created_at, source_hash) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		sessionID, string(entryType), content, metadata, orderIdx, now, hash,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert entry: %w", err)
@@ -116,28 +252,48 @@ func (s *Store) AddEntry(sessionID string, entryType EntryType, content, metadat
 	}, nil
 }
 
-func (s *Store) AddSummary(sessionID, level, content string, tokenEstimate int) (*Summary, error) {
+// AddSummary caches a summary of a session. inputHash identifies what it
+// was generated from, so a stale summary is never served.
+func (s *Store) AddSummary(sessionID, level, inputHash, content string, tokenEstimate int) (*Summary, error) {
 	now := time.Now().UTC()
 	res, err := s.db.Exec(
-		"INSERT INTO summaries (session_id, level, content, token_estimate, created_at) VALUES (?, ?, ?, ?, ?)",
-		sessionID, level, content, tokenEstimate, now,
+		"INSERT INTO summaries (session_id, level, content, token_estimate, created_at, input_hash) VALUES (?, ?, ?, ?, ?, ?)",
+		sessionID, level, content, tokenEstimate, now, inputHash,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert summary: %w", err)
 	}
 	id, _ := res.LastInsertId()
 	return &Summary{
-		ID: id, SessionID: sessionID, Level: level,
+		ID: id, SessionID: sessionID, Level: level, InputHash: inputHash,
 		Content: content, TokenEstimate: tokenEstimate, CreatedAt: now,
 	}, nil
 }
 
+// GetCachedSummary returns the latest summary of a session at the given
+// level generated from inputHash, or nil if there is none.
+func (s *Store) GetCachedSummary(sessionID, level, inputHash string) (*Summary, error) {
+	var sm Summary
+	err := s.db.QueryRow(
+		`SELECT id, session_id, level, input_hash, content, token_estimate, created_at FROM summaries
+		WHERE session_id = ? AND level = ? AND input_hash = ? ORDER BY id DESC LIMIT 1`,
+		sessionID, level, inputHash,
+	).Scan(&sm.ID, &sm.SessionID, &sm.Level, &sm.InputHash, &sm.Content, &sm.TokenEstimate, &sm.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sm, nil
+}
+
 func (s *Store) ListSessions(limit int) ([]Session, error) {
 	if limit <= 0 {
 		limit = 50
 	}
 	rows, err := s.db.Query(
-		"SELECT id, created_at, source, project, label FROM sessions ORDER BY created_at DESC LIMIT ?",
+		"SELECT id, created_at, source, project, label, branch, pinned, priority FROM sessions ORDER BY created_at DESC LIMIT ?",
 		limit,
 	)
 	if err != nil {
@@ -148,7 +304,7 @@ func (s *Store) ListSessions(limit int) ([]Session, error) {
 	var sessions []Session
 	for rows.Next() {
 		var sess Session
-		if err := rows.Scan(&sess.ID, &sess.CreatedAt, &sess.Source, &sess.Project, &sess.Label); err != nil {
+		if err := rows.Scan(&sess.ID, &sess.CreatedAt, &sess.Source, &sess.Project, &sess.Label, &sess.Branch, &sess.Pinned, &sess.Priority); err != nil {
 			return nil, err
 		}
 		sessions = append(sessions, sess)
@@ -159,8 +315,8 @@ func (s *Store) ListSessions(limit int) ([]Session, error) {
 func (s *Store) GetSession(id string) (*Session, error) {
 	var sess Session
 	err := s.db.QueryRow(
-		"SELECT id, created_at, source, project, label FROM sessions WHERE id = ?", id,
-	).Scan(&sess.ID, &sess.CreatedAt, &sess.Source, &sess.Project, &sess.Label)
+		"SELECT id, created_at, source, project, label, branch, pinned, priority FROM sessions WHERE id = ?", id,
+	).Scan(&sess.ID, &sess.CreatedAt, &sess.Source, &sess.Project, &sess.Label, &sess.Branch, &sess.Pinned, &sess.Priority)
 	if err != nil {
 		return nil, err
 	}
@@ -169,7 +325,7 @@ func (s *Store) GetSession(id string) (*Session, error) {
 
 func (s *Store) GetEntries(sessionID string) ([]Entry, error) {
 	rows, err := s.db.Query(
-		"SELECT id, session_id, type, content, metadata, order_idx, created_at FROM entries WHERE session_id = ? ORDER BY order_idx",
+		"SELECT id, session_id, type, content, metadata, order_idx, created_at, pinned, priority FROM entries WHERE session_id = ? ORDER BY order_idx",
 		sessionID,
 	)
 	if err != nil {
@@ -180,7 +336,7 @@ func (s *Store) GetEntries(sessionID string) ([]Entry, error) {
 	var entries []Entry
 	for rows.Next() {
 		var e Entry
-		if err := rows.Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt, &e.Pinned, &e.Priority); err != nil {
 			return nil, err
 		}
 		entries = append(entries, e)
@@ -188,9 +344,351 @@ func (s *Store) GetEntries(sessionID string) ([]Entry, error) {
 	return entries, rows.Err()
 }
 
+func (s *Store) GetEntry(id int64) (*Entry, error) {
+	var e Entry
+	err := s.db.QueryRow(
+		"SELECT id, session_id, type, content, metadata, order_idx, created_at, pinned, priority FROM entries WHERE id = ?", id,
+	).Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt, &e.Pinned, &e.Priority)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Store) GetEntriesByType(entryType EntryType) ([]Entry, error) {
+	rows, err := s.db.Query(
+		"SELECT id, session_id, type, content, metadata, order_idx, created_at, pinned, priority FROM entries WHERE type = ? ORDER BY id",
+		string(entryType),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt, &e.Pinned, &e.Priority); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *Store) UpdateEntryMetadata(id int64, metadata string) error {
+	_, err := s.db.Exec("UPDATE entries SET metadata = ? WHERE id = ?", metadata, id)
+	return err
+}
+
+// UpdateEntryContent replaces an entry's content, recording the old value
+// in the corrections table.
+func (s *Store) UpdateEntryContent(id int64, content string) error {
+	return s.correctEntry(id, "content", content)
+}
+
+// SetEntryType reclassifies an entry, recording the old type in the
+// corrections table.
+func (s *Store) SetEntryType(id int64, entryType EntryType) error {
+	return s.correctEntry(id, "type", string(entryType))
+}
+
+func (s *Store) correctEntry(id int64, field, value string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var entryType, content, hash, sessionID, origin string
+	var orderIdx int
+	err = tx.QueryRow(
+		`SELECT e.type, e.content, e.source_hash, e.session_id, e.order_idx, COALESCE(s.origin, '')
+		FROM entries e LEFT JOIN sessions s ON s.id = e.session_id WHERE e.id = ?`, id,
+	).Scan(&entryType, &content, &hash, &sessionID, &orderIdx, &origin)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("entry #%d not found", id)
+	}
+	if err != nil {
+		return err
+	}
+	original := content
+	if field == "type" {
+		original = entryType
+	}
+	if original == value {
+		return nil
+	}
+	if hash == "" {
+		// captured before corrections were tracked, and not edited since
+		hash = sourceHash(entryScope(sessionID, origin, orderIdx), content)
+	}
+
+	if _, err := tx.Exec("UPDATE entries SET "+field+" = ?, source_hash = ? WHERE id = ?", value, hash, id); err != nil {
+		return fmt.Errorf("update entry: %w", err)
+	}
+	if field == "content" {
+		if err := clearEntryIndex(tx, "entry_id = ?", id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO corrections (entry_id, source_hash, field, original, corrected, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		id, hash, field, original, value, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("record correction: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Corrections returns the audit trail for the entries of a session,
+// oldest first.
+func (s *Store) Corrections(sessionID string) (map[int64][]Correction, error) {
+	rows, err := s.db.Query(
+		`SELECT e.id, c.id, c.entry_id, c.field, c.original, c.corrected, c.created_at
+		FROM corrections c JOIN entries e ON e.id = c.entry_id
+		WHERE e.session_id = ? ORDER BY c.id`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64][]Correction)
+	for rows.Next() {
+		var entryID int64
+		var c Correction
+		if err := rows.Scan(&entryID, &c.ID, &c.EntryID, &c.Field, &c.Original, &c.Corrected, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		result[entryID] = append(result[entryID], c)
+	}
+	return result, rows.Err()
+}
+
+// applyCorrections returns the type and content an entry with the given
+// captured content was last corrected to.
+func applyCorrections(q queryer, hash string, entryType EntryType, content string) (EntryType, string, error) {
+	rows, err := q.Query("SELECT field, corrected FROM corrections WHERE source_hash = ? ORDER BY id", hash)
+	if err != nil {
+		return "", "", fmt.Errorf("load corrections: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field, value string
+		if err := rows.Scan(&field, &value); err != nil {
+			return "", "", err
+		}
+		switch field {
+		case "type":
+			entryType = EntryType(value)
+		case "content":
+			content = value
+		}
+	}
+	return entryType, content, rows.Err()
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// sourceHash identifies captured content within a scope, so corrections
+// carry over only to the same content captured from the same place.
+func sourceHash(scope, content string) string {
+	sum := sha256.Sum256([]byte(scope + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// entryScope is where an entry was captured: its position in the
+// transcript the session came from, or in the session itself when it has
+// no origin and so is never captured again.
+func entryScope(sessionID, origin string, orderIdx int) string {
+	if origin == "" {
+		origin = "session:" + sessionID
+	}
+	return fmt.Sprintf("%s#%d", origin, orderIdx)
+}
+
+// UnindexedEntries returns entries of the given types that have no
+// similarity index yet.
+func (s *Store) UnindexedEntries(types []EntryType) ([]Entry, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(types))
+	for i, t := range types {
+		args[i] = string(t)
+	}
+	rows, err := s.db.Query(
+		`SELECT e.id, e.session_id, e.type, e.content, e.metadata, e.order_idx, e.created_at, e.pinned, e.priority
+		FROM entries e LEFT JOIN entry_signatures sig ON sig.entry_id = e.id
+		WHERE sig.entry_id IS NULL AND e.type IN (?`+strings.Repeat(", ?", len(types)-1)+`) ORDER BY e.id`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt, &e.Pinned, &e.Priority); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// SaveEntryIndex stores an entry's MinHash signature and term counts,
+// replacing what was indexed for it before.
+func (s *Store) SaveEntryIndex(id int64, minhash []byte, terms map[string]int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := clearEntryIndex(tx, "entry_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO entry_signatures (entry_id, minhash, indexed_at) VALUES (?, ?, ?)", id, minhash, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("save signature: %w", err)
+	}
+	for term, count := range terms {
+		if _, err := tx.Exec("INSERT INTO entry_terms (entry_id, term, count) VALUES (?, ?, ?)", id, term, count); err != nil {
+			return fmt.Errorf("save terms: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// PruneEntryIndex drops the index of entries that no longer exist.
+func (s *Store) PruneEntryIndex() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := clearEntryIndex(tx, "entry_id NOT IN (SELECT id FROM entries)"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// EntrySignatures returns the MinHash signature of every indexed entry.
+func (s *Store) EntrySignatures() (map[int64][]byte, error) {
+	rows, err := s.db.Query("SELECT entry_id, minhash FROM entry_signatures")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sigs := make(map[int64][]byte)
+	for rows.Next() {
+		var id int64
+		var sig []byte
+		if err := rows.Scan(&id, &sig); err != nil {
+			return nil, err
+		}
+		sigs[id] = sig
+	}
+	return sigs, rows.Err()
+}
+
+// EntryTerms returns the term counts of every indexed entry.
+func (s *Store) EntryTerms() (map[int64]map[string]int, error) {
+	rows, err := s.db.Query("SELECT entry_id, term, count FROM entry_terms")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := make(map[int64]map[string]int)
+	for rows.Next() {
+		var id int64
+		var term string
+		var count int
+		if err := rows.Scan(&id, &term, &count); err != nil {
+			return nil, err
+		}
+		if terms[id] == nil {
+			terms[id] = make(map[string]int)
+		}
+		terms[id][term] = count
+	}
+	return terms, rows.Err()
+}
+
+func clearEntryIndex(tx *sql.Tx, where string, args ...any) error {
+	for _, table := range []string{"entry_signatures", "entry_terms"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE "+where, args...); err != nil {
+			return fmt.Errorf("clear %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// DeleteEntry removes an entry along with its tags.
+func (s *Store) DeleteEntry(id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM entries WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("delete entry: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("entry #%d not found", id)
+	}
+	if _, err := tx.Exec("DELETE FROM tags WHERE entry_id = ?", id); err != nil {
+		return fmt.Errorf("delete tags: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteSession removes a session with its entries, summaries and tags.
+// Transcripts it was captured from count as unprocessed again.
+func (s *Store) DeleteSession(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM tags WHERE session_id = ? OR entry_id IN (SELECT id FROM entries WHERE session_id = ?)", id, id,
+	); err != nil {
+		return fmt.Errorf("delete tags: %w", err)
+	}
+	for _, table := range []string{"entries", "summaries", "processed_transcripts", "documents"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE session_id = ?", id); err != nil {
+			return fmt.Errorf("delete %s: %w", table, err)
+		}
+	}
+	res, err := tx.Exec("DELETE FROM sessions WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("session %q not found", id)
+	}
+	return tx.Commit()
+}
+
 func (s *Store) GetSummaries(sessionID string) ([]Summary, error) {
 	rows, err := s.db.Query(
-		"SELECT id, session_id, level, content, token_estimate, created_at FROM summaries WHERE session_id = ? ORDER BY created_at",
+		"SELECT id, session_id, level, input_hash, content, token_estimate, created_at FROM summaries WHERE session_id = ? ORDER BY created_at",
 		sessionID,
 	)
 	if err != nil {
@@ -201,7 +699,7 @@ func (s *Store) GetSummaries(sessionID string) ([]Summary, error) {
 	var summaries []Summary
 	for rows.Next() {
 		var sm Summary
-		if err := rows.Scan(&sm.ID, &sm.SessionID, &sm.Level, &sm.Content, &sm.TokenEstimate, &sm.CreatedAt); err != nil {
+		if err := rows.Scan(&sm.ID, &sm.SessionID, &sm.Level, &sm.InputHash, &sm.Content, &sm.TokenEstimate, &sm.CreatedAt); err != nil {
 			return nil, err
 		}
 		summaries = append(summaries, sm)
@@ -210,15 +708,37 @@ func (s *Store) GetSummaries(sessionID string) ([]Summary, error) {
 }
 
 func (s *Store) GetAllEntries(limit int) ([]Entry, error) {
+	return s.GetEntriesMatching(EntryFilter{}, limit)
+}
+
+// GetEntriesMatching returns entries from the sessions the filter selects,
+// highest-priority and then newest session first. Pinned sessions pass
+// the branch and tag filters.
+func (s *Store) GetEntriesMatching(f EntryFilter, limit int) ([]Entry, error) {
 	if limit <= 0 {
 		limit = 500
 	}
+	where, args := sessionConditions(f)
+	if f.Latest > 0 {
+		// the newest N among the sessions the other conditions allow
+		where += " AND s.id IN (SELECT s.id FROM sessions s WHERE " + where + " ORDER BY s.created_at DESC LIMIT ?)"
+		args = append(args, append(append([]any(nil), args...), f.Latest)...)
+	}
+	if f.Tag != "" {
+		where += ` AND (s.pinned = 1
+			OR e.id IN (SELECT entry_id FROM tags WHERE tag = ?)
+			OR s.id IN (SELECT session_id FROM tags WHERE tag = ?))`
+		args = append(args, f.Tag, f.Tag)
+	}
+	args = append(args, limit)
+
 	rows, err := s.db.Query(
-		`SELECT e.id, e.session_id, e.type, e.content, e.metadata, e.order_idx, e.created_at
+		`SELECT e.id, e.session_id, e.type, e.content, e.metadata, e.order_idx, e.created_at, e.pinned, e.priority
 		 FROM entries e
 		 JOIN sessions s ON e.session_id = s.id
-		 ORDER BY s.created_at DESC, e.order_idx
-		 LIMIT ?`, limit,
+		 WHERE `+where+`
+		 ORDER BY s.priority DESC, s.created_at DESC, e.order_idx
+		 LIMIT ?`, args...,
 	)
 	if err != nil {
 		return nil, err
@@ -228,7 +748,7 @@ func (s *Store) GetAllEntries(limit int) ([]Entry, error) {
 	var entries []Entry
 	for rows.Next() {
 		var e Entry
-		if err := rows.Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt, &e.Pinned, &e.Priority); err != nil {
 			return nil, err
 		}
 		entries = append(entries, e)
@@ -236,6 +756,38 @@ func (s *Store) GetAllEntries(limit int) ([]Entry, error) {
 	return entries, rows.Err()
 }
 
+// sessionConditions builds the WHERE clause for the session-level parts of
+// f, against the sessions table aliased as s.
+func sessionConditions(f EntryFilter) (string, []any) {
+	where := "1 = 1"
+	var args []any
+	if f.Branch != "" {
+		where += " AND (s.branch = ? OR s.pinned = 1)"
+		args = append(args, f.Branch)
+	}
+	if len(f.SessionIDs) > 0 {
+		where += " AND s.id IN (?" + strings.Repeat(", ?", len(f.SessionIDs)-1) + ")"
+		for _, id := range f.SessionIDs {
+			args = append(args, id)
+		}
+	}
+	if len(f.Sources) > 0 {
+		where += " AND s.source IN (?" + strings.Repeat(", ?", len(f.Sources)-1) + ")"
+		for _, src := range f.Sources {
+			args = append(args, src)
+		}
+	}
+	if !f.From.IsZero() {
+		where += " AND s.created_at >= ?"
+		args = append(args, f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		where += " AND s.created_at < ?"
+		args = append(args, f.To.UTC())
+	}
+	return where, args
+}
+
 func (s *Store) CountEntries(sessionID string) (int, error) {
 	var count int
 	err := s.db.QueryRow("SELECT COUNT(*) FROM entries WHERE session_id = ?", sessionID).Scan(&count)
@@ -248,6 +800,32 @@ func (s *Store) IsTranscriptProcessed(filePath string) (bool, error) {
 	return count > 0, err
 }
 
+// ProcessedTranscriptSize returns the file size recorded the last time the
+// transcript was captured, so append-only histories can resume from there.
+func (s *Store) ProcessedTranscriptSize(filePath string) (int64, error) {
+	var size int64
+	err := s.db.QueryRow("SELECT file_size FROM processed_transcripts WHERE file_path = ?", filePath).Scan(&size)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return size, err
+}
+
+// ProcessedTranscript returns the session and size recorded the last time
+// the transcript was captured, and false if it never was.
+func (s *Store) ProcessedTranscript(filePath string) (string, int64, bool, error) {
+	var sessionID string
+	var size int64
+	err := s.db.QueryRow("SELECT session_id, file_size FROM processed_transcripts WHERE file_path = ?", filePath).Scan(&sessionID, &size)
+	if err == sql.ErrNoRows {
+		return "", 0, false, nil
+	}
+	if err != nil {
+		return "", 0, false, err
+	}
+	return sessionID, size, true, nil
+}
+
 func (s *Store) MarkTranscriptProcessed(filePath, sessionID string, fileSize int64) error {
 	_, err := s.db.Exec(
 		"INSERT OR REPLACE INTO processed_transcripts (file_path, session_id, file_size, captured_at) VALUES (?, ?, ?, ?)",
@@ -256,6 +834,421 @@ func (s *Store) MarkTranscriptProcessed(filePath, sessionID string, fileSize int
 	return err
 }
 
+func (s *Store) AddDecision(title, rationale string, status DecisionStatus, supersedes, entryID int64) (*Decision, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	res, err := tx.Exec(
+		"INSERT INTO decisions (title, rationale, status, supersedes, entry_id, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		title, rationale, string(status), supersedes, entryID, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert decision: %w", err)
+	}
+	if supersedes > 0 {
+		upd, err := tx.Exec("UPDATE decisions SET status = ? WHERE id = ?", string(DecisionSuperseded), supersedes)
+		if err != nil {
+			return nil, fmt.Errorf("supersede decision: %w", err)
+		}
+		if n, _ := upd.RowsAffected(); n == 0 {
+			return nil, fmt.Errorf("decision #%d not found", supersedes)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	id, _ := res.LastInsertId()
+	return &Decision{
+		ID: id, Title: title, Rationale: rationale, Status: status,
+		Supersedes: supersedes, EntryID: entryID, CreatedAt: now,
+	}, nil
+}
+
+func (s *Store) SetDecisionStatus(id int64, status DecisionStatus) error {
+	res, err := s.db.Exec("UPDATE decisions SET status = ? WHERE id = ?", string(status), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("decision #%d not found", id)
+	}
+	return nil
+}
+
+func (s *Store) ListDecisions() ([]Decision, error) {
+	rows, err := s.db.Query(
+		"SELECT id, title, rationale, status, supersedes, entry_id, created_at FROM decisions ORDER BY id",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []Decision
+	for rows.Next() {
+		var d Decision
+		if err := rows.Scan(&d.ID, &d.Title, &d.Rationale, &d.Status, &d.Supersedes, &d.EntryID, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
+
+func (s *Store) GetSetting(key string) (string, bool, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (s *Store) SetSetting(key, value string) error {
+	_, err := s.db.Exec("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)", key, value)
+	return err
+}
+
+// TagEntry adds tags to an entry; tags it already has are ignored.
+func (s *Store) TagEntry(id int64, tags ...string) error {
+	return s.addTags(id, "", tags)
+}
+
+// TagSession adds tags to a session, which tags all of its entries.
+func (s *Store) TagSession(id string, tags ...string) error {
+	return s.addTags(0, id, tags)
+}
+
+func (s *Store) addTags(entryID int64, sessionID string, tags []string) error {
+	now := time.Now().UTC()
+	for _, t := range tags {
+		if _, err := s.db.Exec(
+			"INSERT OR IGNORE INTO tags (entry_id, session_id, tag, created_at) VALUES (?, ?, ?, ?)",
+			entryID, sessionID, t, now,
+		); err != nil {
+			return fmt.Errorf("insert tag: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) UntagEntry(id int64, tags ...string) error {
+	return s.removeTags(id, "", tags)
+}
+
+func (s *Store) UntagSession(id string, tags ...string) error {
+	return s.removeTags(0, id, tags)
+}
+
+func (s *Store) removeTags(entryID int64, sessionID string, tags []string) error {
+	for _, t := range tags {
+		if _, err := s.db.Exec(
+			"DELETE FROM tags WHERE entry_id = ? AND session_id = ? AND tag = ?", entryID, sessionID, t,
+		); err != nil {
+			return fmt.Errorf("delete tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetEntryTags replaces the entry's own tags; tags inherited from its
+// session are left alone.
+func (s *Store) SetEntryTags(id int64, tags []string) error {
+	return s.setTags(id, "", tags)
+}
+
+func (s *Store) SetSessionTags(id string, tags []string) error {
+	return s.setTags(0, id, tags)
+}
+
+func (s *Store) setTags(entryID int64, sessionID string, tags []string) error {
+	if _, err := s.db.Exec("DELETE FROM tags WHERE entry_id = ? AND session_id = ?", entryID, sessionID); err != nil {
+		return fmt.Errorf("clear tags: %w", err)
+	}
+	return s.addTags(entryID, sessionID, tags)
+}
+
+// SessionTags returns the tags set on a session itself.
+func (s *Store) SessionTags(id string) ([]string, error) {
+	rows, err := s.db.Query("SELECT tag FROM tags WHERE entry_id = 0 AND session_id = ? ORDER BY tag", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// EntryTags returns the tags of each entry in a session, including the
+// ones inherited from the session itself.
+func (s *Store) EntryTags(sessionID string) (map[int64][]string, error) {
+	rows, err := s.db.Query(
+		`SELECT e.id, t.tag FROM tags t
+		 JOIN entries e ON (t.entry_id = e.id OR t.session_id = e.session_id)
+		 WHERE e.session_id = ?
+		 ORDER BY t.tag`, sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	return tags, rows.Err()
+}
+
+// ListTags returns every tag with how many entries and sessions carry it.
+func (s *Store) ListTags() ([]TagCount, error) {
+	rows, err := s.db.Query(
+		`SELECT tag, SUM(entry_id != 0), SUM(session_id != '') FROM tags GROUP BY tag ORDER BY tag`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []TagCount
+	for rows.Next() {
+		var c TagCount
+		if err := rows.Scan(&c.Tag, &c.Entries, &c.Sessions); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// SetEntryPin pins or unpins an entry. Pinned entries appear verbatim in
+// every briefing, highest priority first.
+func (s *Store) SetEntryPin(id int64, pinned bool, priority int) error {
+	res, err := s.db.Exec("UPDATE entries SET pinned = ?, priority = ? WHERE id = ?", pinned, priority, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("entry #%d not found", id)
+	}
+	return nil
+}
+
+// SetSessionPin pins or unpins a session. A pinned session is kept by every
+// --branch and --tag filter; priority orders sessions in the briefing.
+func (s *Store) SetSessionPin(id string, pinned bool, priority int) error {
+	res, err := s.db.Exec("UPDATE sessions SET pinned = ?, priority = ? WHERE id = ?", pinned, priority, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("session %q not found", id)
+	}
+	return nil
+}
+
+func (s *Store) GetPinnedEntries() ([]Entry, error) {
+	rows, err := s.db.Query(
+		"SELECT id, session_id, type, content, metadata, order_idx, created_at, pinned, priority FROM entries WHERE pinned = 1 ORDER BY priority DESC, id",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.Type, &e.Content, &e.Metadata, &e.OrderIdx, &e.CreatedAt, &e.Pinned, &e.Priority); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetDocument returns the stored version of a captured doc, or nil if the
+// path hasn't been captured yet.
+func (s *Store) GetDocument(path string) (*Document, error) {
+	var d Document
+	err := s.db.QueryRow(
+		"SELECT path, hash, session_id, updated_at FROM documents WHERE path = ?", path,
+	).Scan(&d.Path, &d.Hash, &d.SessionID, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ReplaceDocument swaps the entries of a doc's session for the given ones and
+// records the new hash. The session is moved to the present so the current
+// version of the doc sorts with recent context. Pins and tags follow a
+// chunk to its new entry: to the chunk with the same content if there is
+// one, else to the chunk at the same position.
+func (s *Store) ReplaceDocument(path, hash, sessionID string, entries []Entry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type oldChunk struct {
+		hash     string
+		order    int
+		pinned   bool
+		priority int
+		tags     []string
+		used     bool
+	}
+	var olds []*oldChunk
+	byID := make(map[int64]*oldChunk)
+	rows, err := tx.Query("SELECT id, source_hash, order_idx, pinned, priority FROM entries WHERE session_id = ?", sessionID)
+	if err != nil {
+		return fmt.Errorf("load doc entries: %w", err)
+	}
+	for rows.Next() {
+		var id int64
+		c := &oldChunk{}
+		if err := rows.Scan(&id, &c.hash, &c.order, &c.pinned, &c.priority); err != nil {
+			rows.Close()
+			return err
+		}
+		olds = append(olds, c)
+		byID[id] = c
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	tagRows, err := tx.Query(
+		"SELECT entry_id, tag FROM tags WHERE entry_id IN (SELECT id FROM entries WHERE session_id = ?) ORDER BY id", sessionID,
+	)
+	if err != nil {
+		return fmt.Errorf("load doc tags: %w", err)
+	}
+	for tagRows.Next() {
+		var id int64
+		var tag string
+		if err := tagRows.Scan(&id, &tag); err != nil {
+			tagRows.Close()
+			return err
+		}
+		byID[id].tags = append(byID[id].tags, tag)
+	}
+	tagRows.Close()
+	if err := tagRows.Err(); err != nil {
+		return err
+	}
+	match := func(entryHash string, order int) *oldChunk {
+		for _, c := range olds {
+			if !c.used && c.hash == entryHash {
+				c.used = true
+				return c
+			}
+		}
+		for _, c := range olds {
+			if !c.used && c.order == order {
+				c.used = true
+				return c
+			}
+		}
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(
+		"DELETE FROM tags WHERE entry_id IN (SELECT id FROM entries WHERE session_id = ?)", sessionID,
+	); err != nil {
+		return fmt.Errorf("clear doc tags: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM entries WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("clear doc entries: %w", err)
+	}
+	for _, e := range entries {
+		// paragraphs move around as a doc is edited, so only the path scopes them
+		entryHash := sourceHash("doc:"+path, e.Content)
+		entryType, content, err := applyCorrections(tx, entryHash, e.Type, e.Content)
+		if err != nil {
+			return err
+		}
+		var pinned bool
+		var priority int
+		var tags []string
+		if old := match(entryHash, e.OrderIdx); old != nil {
+			pinned, priority, tags = old.pinned, old.priority, old.tags
+		}
+		res, err := tx.Exec(
+			"INSERT INTO entries (session_id, type, content, metadata, order_idx, created_at, source_hash, pinned, priority) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			sessionID, string(entryType), content, e.Metadata, e.OrderIdx, now, entryHash, pinned, priority,
+		)
+		if err != nil {
+			return fmt.Errorf("insert entry: %w", err)
+		}
+		id, _ := res.LastInsertId()
+		for _, tag := range tags {
+			if _, err := tx.Exec(
+				"INSERT OR IGNORE INTO tags (entry_id, session_id, tag, created_at) VALUES (?, '', ?, ?)", id, tag, now,
+			); err != nil {
+				return fmt.Errorf("carry over tag: %w", err)
+			}
+		}
+	}
+	if _, err := tx.Exec("UPDATE sessions SET created_at = ? WHERE id = ?", now, sessionID); err != nil {
+		return fmt.Errorf("touch session: %w", err)
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO documents (path, hash, session_id, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET hash = excluded.hash, session_id = excluded.session_id, updated_at = excluded.updated_at`,
+		path, hash, sessionID, now,
+	); err != nil {
+		return fmt.Errorf("save document: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListDocuments returns every captured doc, by path.
+func (s *Store) ListDocuments() ([]Document, error) {
+	rows, err := s.db.Query("SELECT path, hash, session_id, updated_at FROM documents ORDER BY path")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var d Document
+		if err := rows.Scan(&d.Path, &d.Hash, &d.SessionID, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
 func generateID() (string, error) {
 	b := make([]byte, 8)
 	if _, err := rand.Read(b); err != nil {
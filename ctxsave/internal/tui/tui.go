@@ -0,0 +1,502 @@
+// Package tui implements 'ctxsave ui', a terminal interface for browsing
+// sessions and curating their entries with a live briefing preview.
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"ctxsave/internal/compress"
+	"ctxsave/internal/generate"
+	"ctxsave/internal/store"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type pane int
+
+const (
+	paneSessions pane = iota
+	paneEntries
+	panePreview
+)
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeConfirmDelete
+	modeRetag
+	modePickType
+)
+
+// previewMsg carries a regenerated briefing. seq tells a stale result,
+// finished after a newer one was requested, from the current one.
+type previewMsg struct {
+	seq    int
+	prompt string
+	tokens int
+}
+
+type editorFinishedMsg struct {
+	entryID int64
+	path    string
+	err     error
+}
+
+type Model struct {
+	st      *store.Store
+	project string
+
+	sessions []store.Session
+	entries  []store.Entry
+	tags     map[int64][]string
+	sessIdx  int
+	entryIdx int
+
+	focus    pane
+	mode     mode
+	models   []generate.ModelProfile
+	modelIdx int
+	typeIdx  int
+
+	preview    viewport.Model
+	prompt     string
+	tokens     int
+	previewSeq int
+	input      textinput.Model
+	status     string
+
+	width, height int
+}
+
+// New builds the UI over an open store. modelKey picks the model the
+// preview and token count are generated for.
+func New(st *store.Store, project, modelKey string) *Model {
+	m := &Model{
+		st:      st,
+		project: project,
+		models:  generate.ListModels(),
+		preview: viewport.New(40, 10),
+		input:   textinput.New(),
+	}
+	for i, p := range m.models {
+		if p.Key == modelKey {
+			m.modelIdx = i
+		}
+	}
+	m.input.Prompt = "tags: "
+	m.input.CharLimit = 200
+	return m
+}
+
+// Run starts the UI in the terminal's alternate screen and blocks until
+// the user quits.
+func Run(st *store.Store, project, modelKey string) error {
+	m := New(st, project, modelKey)
+	if err := m.reloadSessions(); err != nil {
+		return err
+	}
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *Model) Init() tea.Cmd {
+	return m.refreshPreview()
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+
+	case previewMsg:
+		if msg.seq == m.previewSeq {
+			m.prompt, m.tokens = msg.prompt, msg.tokens
+			m.preview.SetContent(wrap(m.prompt, m.preview.Width))
+		}
+		return m, nil
+
+	case editorFinishedMsg:
+		defer os.Remove(msg.path)
+		if msg.err != nil {
+			m.status = fmt.Sprintf("editor: %v", msg.err)
+			return m, nil
+		}
+		data, err := os.ReadFile(msg.path)
+		if err != nil {
+			m.status = fmt.Sprintf("read edited entry: %v", err)
+			return m, nil
+		}
+		content := strings.TrimRight(string(data), "\n")
+		if strings.TrimSpace(content) == "" {
+			m.status = "Edited content is empty — entry left unchanged"
+			return m, nil
+		}
+		if cur := m.selectedEntry(); cur != nil && cur.ID == msg.entryID && cur.Content == content {
+			m.status = "No changes"
+			return m, nil
+		}
+		return m, m.apply(m.st.UpdateEntryContent(msg.entryID, content), fmt.Sprintf("Edited entry #%d", msg.entryID))
+
+	case tea.KeyMsg:
+		switch m.mode {
+		case modeConfirmDelete:
+			return m, m.updateConfirm(msg)
+		case modeRetag:
+			return m, m.updateRetag(msg)
+		case modePickType:
+			return m, m.updatePickType(msg)
+		}
+		return m, m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+func (m *Model) updateBrowse(msg tea.KeyMsg) tea.Cmd {
+	m.status = ""
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % 3
+		return nil
+	case "shift+tab":
+		m.focus = (m.focus + 2) % 3
+		return nil
+	case "m":
+		m.modelIdx = (m.modelIdx + 1) % len(m.models)
+		return m.refreshPreview()
+	case "r":
+		return m.apply(nil, "Reloaded")
+	}
+
+	if m.focus == panePreview {
+		var cmd tea.Cmd
+		m.preview, cmd = m.preview.Update(msg)
+		return cmd
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "enter", "right", "l":
+		if m.focus == paneSessions {
+			m.focus = paneEntries
+		}
+	case "left", "h", "esc":
+		if m.focus == paneEntries {
+			m.focus = paneSessions
+		}
+	case "d":
+		if m.selectedTarget() != "" {
+			m.mode = modeConfirmDelete
+		}
+	case "p":
+		return m.togglePin()
+	case "t":
+		return m.startRetag()
+	case "e":
+		return m.startEdit()
+	case "c":
+		if e := m.selectedEntry(); e != nil && m.focus == paneEntries {
+			m.typeIdx = 0
+			for i, t := range store.EntryTypes {
+				if t == e.Type {
+					m.typeIdx = i
+				}
+			}
+			m.mode = modePickType
+		}
+	}
+	return nil
+}
+
+func (m *Model) updateConfirm(msg tea.KeyMsg) tea.Cmd {
+	m.mode = modeBrowse
+	if msg.String() != "y" {
+		m.status = "Delete cancelled"
+		return nil
+	}
+	if m.focus == paneSessions {
+		if s := m.selectedSession(); s != nil {
+			return m.apply(m.st.DeleteSession(s.ID), fmt.Sprintf("Deleted session %s", s.ID))
+		}
+		return nil
+	}
+	if e := m.selectedEntry(); e != nil {
+		return m.apply(m.st.DeleteEntry(e.ID), fmt.Sprintf("Deleted entry #%d", e.ID))
+	}
+	return nil
+}
+
+func (m *Model) updateRetag(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		m.input.Blur()
+		return nil
+	case "enter":
+		m.mode = modeBrowse
+		m.input.Blur()
+		tags := splitTags(m.input.Value())
+		if m.focus == paneSessions {
+			if s := m.selectedSession(); s != nil {
+				return m.apply(m.st.SetSessionTags(s.ID, tags), fmt.Sprintf("Retagged session %s", s.ID))
+			}
+		} else if e := m.selectedEntry(); e != nil {
+			return m.apply(m.st.SetEntryTags(e.ID, tags), fmt.Sprintf("Retagged entry #%d", e.ID))
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return cmd
+}
+
+func (m *Model) updatePickType(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "left", "h", "up", "k":
+		m.typeIdx = (m.typeIdx + len(store.EntryTypes) - 1) % len(store.EntryTypes)
+	case "right", "l", "down", "j", "c":
+		m.typeIdx = (m.typeIdx + 1) % len(store.EntryTypes)
+	case "esc", "q":
+		m.mode = modeBrowse
+	case "enter":
+		m.mode = modeBrowse
+		if e := m.selectedEntry(); e != nil {
+			t := store.EntryTypes[m.typeIdx]
+			if t != e.Type {
+				return m.apply(m.st.SetEntryType(e.ID, t), fmt.Sprintf("Entry #%d is now %s", e.ID, t))
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Model) move(delta int) {
+	switch m.focus {
+	case paneSessions:
+		next := clamp(m.sessIdx+delta, len(m.sessions))
+		if next != m.sessIdx {
+			m.sessIdx = next
+			m.entryIdx = 0
+			// the briefing covers every session, so the preview stays as is
+			if err := m.reloadEntries(); err != nil {
+				m.status = "Error: " + err.Error()
+			}
+		}
+	case paneEntries:
+		m.entryIdx = clamp(m.entryIdx+delta, len(m.entries))
+	}
+}
+
+func (m *Model) togglePin() tea.Cmd {
+	if m.focus == paneSessions {
+		if s := m.selectedSession(); s != nil {
+			return m.apply(m.st.SetSessionPin(s.ID, !s.Pinned, s.Priority), pinStatus(!s.Pinned, "session "+s.ID))
+		}
+		return nil
+	}
+	if e := m.selectedEntry(); e != nil {
+		return m.apply(m.st.SetEntryPin(e.ID, !e.Pinned, e.Priority), pinStatus(!e.Pinned, fmt.Sprintf("entry #%d", e.ID)))
+	}
+	return nil
+}
+
+func (m *Model) startRetag() tea.Cmd {
+	var current []string
+	switch m.focus {
+	case paneSessions:
+		s := m.selectedSession()
+		if s == nil {
+			return nil
+		}
+		current, _ = m.st.SessionTags(s.ID)
+	case paneEntries:
+		e := m.selectedEntry()
+		if e == nil {
+			return nil
+		}
+		inherited, _ := m.st.SessionTags(e.SessionID)
+		for _, t := range m.tags[e.ID] {
+			if !contains(inherited, t) {
+				current = append(current, t)
+			}
+		}
+	default:
+		return nil
+	}
+	m.mode = modeRetag
+	m.input.SetValue(strings.Join(current, ", "))
+	m.input.CursorEnd()
+	return m.input.Focus()
+}
+
+func (m *Model) startEdit() tea.Cmd {
+	e := m.selectedEntry()
+	if e == nil || m.focus != paneEntries {
+		return nil
+	}
+	f, err := os.CreateTemp("", "ctxsave-entry-*.md")
+	if err != nil {
+		m.status = fmt.Sprintf("edit: %v", err)
+		return nil
+	}
+	_, err = f.WriteString(e.Content + "\n")
+	f.Close()
+	if err != nil {
+		m.status = fmt.Sprintf("edit: %v", err)
+		return nil
+	}
+	id, path := e.ID, f.Name()
+	return tea.ExecProcess(EditorCommand(path), func(err error) tea.Msg {
+		return editorFinishedMsg{entryID: id, path: path, err: err}
+	})
+}
+
+// EditorCommand opens path in $VISUAL or $EDITOR, falling back to vi.
+func EditorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// the variable may carry flags, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	return exec.Command(fields[0], append(fields[1:], path)...)
+}
+
+// apply reports the outcome of a change, reloads what it touched and
+// regenerates the preview.
+func (m *Model) apply(err error, done string) tea.Cmd {
+	if err != nil {
+		m.status = "Error: " + err.Error()
+		return nil
+	}
+	if done != "" {
+		m.status = done
+	}
+	if err := m.reloadSessions(); err != nil {
+		m.status = "Error: " + err.Error()
+	}
+	return m.refreshPreview()
+}
+
+func (m *Model) reloadSessions() error {
+	sessions, err := m.st.ListSessions(500)
+	if err != nil {
+		return err
+	}
+	m.sessions = sessions
+	m.sessIdx = clamp(m.sessIdx, len(sessions))
+	return m.reloadEntries()
+}
+
+func (m *Model) reloadEntries() error {
+	m.entries, m.tags = nil, nil
+	if s := m.selectedSession(); s != nil {
+		entries, err := m.st.GetEntries(s.ID)
+		if err != nil {
+			return err
+		}
+		tags, err := m.st.EntryTags(s.ID)
+		if err != nil {
+			return err
+		}
+		m.entries, m.tags = entries, tags
+	}
+	m.entryIdx = clamp(m.entryIdx, len(m.entries))
+	return nil
+}
+
+// refreshPreview regenerates the briefing in the background. Only the
+// result of the latest request is shown.
+func (m *Model) refreshPreview() tea.Cmd {
+	m.previewSeq++
+	seq, model := m.previewSeq, m.models[m.modelIdx]
+	st, project := m.st, m.project
+	return func() tea.Msg {
+		// the preview is regenerated on every change, too often to wait on an LLM
+		prompt, err := generate.NewPromptGenerator(st, project).Generate(generate.GenerateOptions{ModelKey: model.Key, NoLLM: true})
+		if err != nil {
+			prompt = err.Error()
+		}
+		return previewMsg{seq: seq, prompt: prompt, tokens: compress.EstimateTokens(prompt, model.Family)}
+	}
+}
+
+func (m *Model) selectedSession() *store.Session {
+	if m.sessIdx < len(m.sessions) {
+		return &m.sessions[m.sessIdx]
+	}
+	return nil
+}
+
+func (m *Model) selectedEntry() *store.Entry {
+	if m.entryIdx < len(m.entries) {
+		return &m.entries[m.entryIdx]
+	}
+	return nil
+}
+
+// selectedTarget describes what a delete in the focused pane would remove.
+func (m *Model) selectedTarget() string {
+	switch m.focus {
+	case paneSessions:
+		if s := m.selectedSession(); s != nil {
+			return fmt.Sprintf("session %s and its %d entries", s.ID, len(m.entries))
+		}
+	case paneEntries:
+		if e := m.selectedEntry(); e != nil {
+			return fmt.Sprintf("entry #%d", e.ID)
+		}
+	}
+	return ""
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if t = strings.TrimPrefix(t, "#"); t != "" && !contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func pinStatus(pinned bool, what string) string {
+	if pinned {
+		return "Pinned " + what
+	}
+	return "Unpinned " + what
+}
+
+func clamp(i, n int) int {
+	if i >= n {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"ctxsave/internal/store"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	paneStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("240"))
+	focusStyle    = paneStyle.BorderForeground(lipgloss.Color("63"))
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("63"))
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	pinStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	statusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+)
+
+// layout sizes the panes from the terminal size: sessions and entries
+// share the left half, the briefing preview takes the right.
+func (m *Model) layout() {
+	left := m.width / 2
+	m.preview.Width = max(m.width-left-4, 10)
+	m.preview.Height = max(m.bodyHeight()-3, 3)
+	m.preview.SetContent(wrap(m.prompt, m.preview.Width))
+}
+
+// bodyHeight is what remains for the panes after the header and footer.
+func (m *Model) bodyHeight() int {
+	return max(m.height-4, 6)
+}
+
+func (m *Model) View() string {
+	if m.width == 0 {
+		return "Loading…"
+	}
+
+	model := m.models[m.modelIdx]
+	header := titleStyle.Render(fmt.Sprintf("ctxsave · %s", m.project)) +
+		dimStyle.Render(fmt.Sprintf("   %s · ~%d tokens", model.Name, m.tokens))
+
+	body := m.bodyHeight()
+	leftWidth := m.width / 2
+	sessH := body / 3
+	entryH := body - sessH
+
+	sessions := m.frame(paneSessions, "Sessions", m.sessionLines(leftWidth-4, sessH-3), leftWidth, sessH)
+	entries := m.frame(paneEntries, "Entries", m.entryLines(leftWidth-4, entryH-3), leftWidth, entryH)
+	preview := m.frame(panePreview, "Briefing preview", strings.Split(m.preview.View(), "\n"), m.width-leftWidth, body)
+
+	columns := lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.JoinVertical(lipgloss.Left, sessions, entries),
+		preview,
+	)
+	return lipgloss.JoinVertical(lipgloss.Left, header, columns, m.footer())
+}
+
+func (m *Model) frame(p pane, title string, lines []string, width, height int) string {
+	style := paneStyle
+	if m.focus == p {
+		style = focusStyle
+	}
+	content := titleStyle.Render(title) + "\n" + strings.Join(lines, "\n")
+	return style.Width(width - 2).Height(height - 2).MaxHeight(height).Render(content)
+}
+
+func (m *Model) sessionLines(width, height int) []string {
+	if len(m.sessions) == 0 {
+		return []string{dimStyle.Render("No sessions yet — run 'ctxsave capture' first")}
+	}
+	var lines []string
+	for i := scrollStart(m.sessIdx, len(m.sessions), height); i < len(m.sessions) && len(lines) < height; i++ {
+		s := m.sessions[i]
+		line := fmt.Sprintf("%s %-8s %s", s.CreatedAt.Local().Format("01-02 15:04"), s.Source, s.Label)
+		lines = append(lines, m.row(line, s.Pinned, i == m.sessIdx, m.focus == paneSessions, width))
+	}
+	return lines
+}
+
+func (m *Model) entryLines(width, height int) []string {
+	if len(m.entries) == 0 {
+		return []string{dimStyle.Render("No entries in this session")}
+	}
+	var lines []string
+	for i := scrollStart(m.entryIdx, len(m.entries), height); i < len(m.entries) && len(lines) < height; i++ {
+		e := m.entries[i]
+		line := fmt.Sprintf("#%d [%s] %s", e.ID, e.Type, oneLine(e.Content))
+		if tags := m.tags[e.ID]; len(tags) > 0 {
+			line += "  #" + strings.Join(tags, " #")
+		}
+		lines = append(lines, m.row(line, e.Pinned, i == m.entryIdx, m.focus == paneEntries, width))
+	}
+	return lines
+}
+
+func (m *Model) row(line string, pinned, selected, focused bool, width int) string {
+	marker := "  "
+	if pinned {
+		marker = pinStyle.Render("★ ")
+	}
+	line = truncate(line, width-2)
+	if selected && focused {
+		return marker + selectedStyle.Render(line)
+	}
+	if selected {
+		return marker + titleStyle.Render(line)
+	}
+	return marker + line
+}
+
+func (m *Model) footer() string {
+	switch m.mode {
+	case modeConfirmDelete:
+		return statusStyle.Render(fmt.Sprintf("Delete %s? (y/n)", m.selectedTarget()))
+	case modeRetag:
+		return m.input.View() + dimStyle.Render("   enter save · esc cancel")
+	case modePickType:
+		var types []string
+		for i, t := range store.EntryTypes {
+			if i == m.typeIdx {
+				types = append(types, selectedStyle.Render(string(t)))
+			} else {
+				types = append(types, string(t))
+			}
+		}
+		return "type: " + strings.Join(types, " ") + dimStyle.Render("   ←/→ choose · enter set · esc cancel")
+	}
+	help := dimStyle.Render("tab pane · j/k move · d delete · p pin · e edit · t retag · c type · m model · r reload · q quit")
+	if m.status != "" {
+		return statusStyle.Render(m.status) + "   " + help
+	}
+	return help
+}
+
+// scrollStart keeps the selected row visible in a list of the given height.
+func scrollStart(selected, total, height int) int {
+	if height <= 0 || total <= height || selected < height/2 {
+		return 0
+	}
+	start := selected - height/2
+	if start > total-height {
+		start = total - height
+	}
+	return start
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, width int) string {
+	if width <= 1 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
+
+// wrap hard-wraps text to the preview width so long briefing lines stay
+// inside the pane.
+func wrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	var sb strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		r := []rune(line)
+		for len(r) > width {
+			sb.WriteString(string(r[:width]))
+			sb.WriteByte('\n')
+			r = r[width:]
+		}
+		sb.WriteString(string(r))
+	}
+	return sb.String()
+}